@@ -56,3 +56,18 @@ func DepTextToType(depText string) int {
 	}
 	return UNDEF
 }
+
+func MinStartDelay(depType int, durationA int, durationB int) int {
+	// Minimum distance from the start of task A to the start of task B so that the dependency holds
+	switch depType {
+	case SS:
+		return 0
+	case SF:
+		return 2 - durationB
+	case FS:
+		return durationA
+	case FF:
+		return durationA - durationB
+	}
+	return 0
+}
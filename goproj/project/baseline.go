@@ -0,0 +1,138 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"fmt"
+	"strings"
+)
+
+type baselineEntry struct {
+	startT  int
+	finishT int
+}
+
+type baseline struct {
+	tasks map[string]baselineEntry
+}
+
+type TaskVariance struct {
+	Id              string
+	Milestone       bool
+	BaselineStartT  int
+	BaselineFinishT int
+	StartVariance   int
+	FinishVariance  int
+}
+
+type BaselineComparison struct {
+	BaselineMakespan  int
+	Makespan          int
+	MakespanChange    int
+	Tasks             []TaskVariance
+	SlippedMilestones []TaskVariance
+}
+
+func (p *Project) SetMilestone(taskId string) string {
+	t, exists := p.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	t.milestone = true
+	p.tasks[taskId] = t
+	return ""
+}
+
+func (p *Project) SetBaselineTask(taskId string, startT int, finishT int) string {
+	_, exists := p.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	if startT < 0 || finishT < startT {
+		return fmt.Sprintf("Task '%s' has an inconsistent baseline", taskId)
+	}
+	if p.baseline == nil {
+		p.baseline = &baseline{map[string]baselineEntry{}}
+	}
+	p.baseline.tasks[taskId] = baselineEntry{startT, finishT}
+	return ""
+}
+
+func (p *Project) SaveBaseline() string {
+	if p.makespan == common.UNDEF {
+		return "Missing makespan, probably empty schedule"
+	}
+	p.baseline = &baseline{map[string]baselineEntry{}}
+	for id, t := range p.tasks {
+		p.baseline.tasks[id] = baselineEntry{t.startT, t.finishT}
+	}
+	return ""
+}
+
+func (b *baseline) makespan() int {
+	makespan := 0
+	for _, entry := range b.tasks {
+		if entry.finishT+1 > makespan {
+			makespan = entry.finishT + 1
+		}
+	}
+	return makespan
+}
+
+func (p *Project) CompareToBaseline() (*BaselineComparison, string) {
+	if p.baseline == nil {
+		return nil, "Project has no baseline"
+	}
+	if p.makespan == common.UNDEF {
+		return nil, "Missing makespan, probably empty schedule"
+	}
+	baselineMakespan := p.baseline.makespan()
+	comparison := BaselineComparison{baselineMakespan, p.makespan, p.makespan - baselineMakespan, []TaskVariance{}, []TaskVariance{}}
+	for _, id := range p.sortedTaskIds() {
+		entry, exists := p.baseline.tasks[id]
+		if !exists {
+			continue
+		}
+		t := p.tasks[id]
+		v := TaskVariance{id, t.milestone, entry.startT, entry.finishT, t.startT - entry.startT, t.finishT - entry.finishT}
+		comparison.Tasks = append(comparison.Tasks, v)
+		if t.milestone && v.FinishVariance > 0 {
+			comparison.SlippedMilestones = append(comparison.SlippedMilestones, v)
+		}
+	}
+	return &comparison, ""
+}
+
+func (c *BaselineComparison) varianceByTask() map[string]TaskVariance {
+	variances := map[string]TaskVariance{}
+	for _, v := range c.Tasks {
+		variances[v.Id] = v
+	}
+	return variances
+}
+
+func (c *BaselineComparison) writeSummaryXML(w *strings.Builder, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	fmt.Fprintf(w, "%s<baseline-comparison baseline-makespan=\"%d\" makespan-change=\"%d\" slipped-milestones=\"%d\">\n", indent, c.BaselineMakespan, c.MakespanChange, len(c.SlippedMilestones))
+	for _, v := range c.SlippedMilestones {
+		fmt.Fprintf(w, "%s<slipped-milestone task-id=\"%s\" baseline-finish-t=\"%d\" finish-variance=\"%d\"/>\n", indent+xmlIndent, v.Id, v.BaselineFinishT, v.FinishVariance)
+	}
+	fmt.Fprintf(w, "%s</baseline-comparison>\n", indent)
+}
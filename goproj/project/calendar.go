@@ -19,6 +19,7 @@ along with this program.  If not, see <https://www.gnu.org/licenses/>.
 package project
 
 import (
+	"fmt"
 	"strconv"
 	"time"
 )
@@ -109,3 +110,29 @@ func (c *calendar) buildDateMap(days int) {
 		wd = (wd + 1) % daysPerWeek
 	}
 }
+
+func (c *calendar) isWorkday(date time.Time) bool {
+	_, isIdleDate := c.idleDates[date.Format("2006-01-02")]
+	return c.activeWeekDays[date.Weekday()] && !isIdleDate
+}
+
+func (c *calendar) dateToOffset(date string) (int, string) {
+	target, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return -1, err.Error()
+	}
+	kickOff, _ := time.Parse("2006-01-02", c.kickOffDate)
+	if target.Before(kickOff) {
+		return -1, fmt.Sprintf("Date '%s' precedes the kick-off date", date)
+	}
+	if !c.isWorkday(target) {
+		return -1, fmt.Sprintf("Date '%s' is not a workday", date)
+	}
+	offset := 0
+	for d := kickOff; d.Before(target); d = d.AddDate(0, 0, 1) {
+		if c.isWorkday(d) {
+			offset++
+		}
+	}
+	return offset, ""
+}
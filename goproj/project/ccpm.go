@@ -0,0 +1,237 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	CUT_AND_PASTE = iota
+	ROOT_SQUARE_ERROR
+)
+
+const DEFAULT_CUT_PERCENT = 50
+
+type Buffer struct {
+	Id            string
+	Type          string
+	Size          int
+	FeedingTaskId string
+	ChainTaskId   string
+	StartT        int
+	StartDate     string
+	FinishT       int
+	FinishDate    string
+}
+
+type CriticalChainReport struct {
+	Sizing     int
+	CutPercent int
+	Chain      []string
+	Buffers    []Buffer
+	Schedule   *Project
+}
+
+func bufferSizingTextToType(sizingText string) int {
+	switch strings.ToLower(sizingText) {
+	case "", "cut-and-paste":
+		return CUT_AND_PASTE
+	case "root-square-error":
+		return ROOT_SQUARE_ERROR
+	}
+	return common.UNDEF
+}
+
+func bufferSizingTypeToText(sizing int) string {
+	switch sizing {
+	case CUT_AND_PASTE:
+		return "cut-and-paste"
+	case ROOT_SQUARE_ERROR:
+		return "root-square-error"
+	}
+	return ""
+}
+
+func sizeBuffer(safeties []int, sizing int) int {
+	if sizing == ROOT_SQUARE_ERROR {
+		sumSquares := 0
+		for _, x := range safeties {
+			sumSquares += x * x
+		}
+		return int(math.Ceil(math.Sqrt(float64(sumSquares))))
+	}
+	sum := 0
+	for _, x := range safeties {
+		sum += x
+	}
+	return (sum + 1) / 2 // Half of the safety removed from the chain
+}
+
+func (p *Project) uniqueTaskId(id string) string {
+	for {
+		_, exists := p.tasks[id]
+		if !exists {
+			return id
+		}
+		id += "_"
+	}
+}
+
+func (p *Project) dependencyPredecessors() map[string][]string {
+	preds := map[string][]string{}
+	for _, id := range p.sortedTaskIds() {
+		for succ := range p.tasks[id].taskDependencies {
+			preds[succ] = append(preds[succ], id)
+		}
+	}
+	return preds
+}
+
+func (p *Project) longestFeedingPath(id string, onChain map[string]bool, preds map[string][]string, memo map[string][]string) []string {
+	// Longest path of tasks off the critical chain ending at the given task
+	path, known := memo[id]
+	if known {
+		return path
+	}
+	best := []string{}
+	bestLength := 0
+	for _, pred := range preds[id] {
+		if onChain[pred] {
+			continue
+		}
+		candidate := p.longestFeedingPath(pred, onChain, preds, memo)
+		length := 0
+		for _, taskId := range candidate {
+			length += p.tasks[taskId].duration
+		}
+		if length > bestLength {
+			best, bestLength = candidate, length
+		}
+	}
+	path = append(append([]string{}, best...), id)
+	memo[id] = path
+	return path
+}
+
+func (p *Project) CriticalChain(sizing string, cutPercent int) (*CriticalChainReport, string) {
+	sizingType := bufferSizingTextToType(sizing)
+	if sizingType == common.UNDEF {
+		return nil, fmt.Sprintf("Unknown buffer sizing method '%s'", sizing)
+	}
+	if cutPercent <= 0 {
+		cutPercent = DEFAULT_CUT_PERCENT
+	}
+	if cutPercent >= 100 {
+		return nil, "Estimate cut must be below 100%"
+	}
+	c := p.clone()
+	if !c.Schedule(FIND_OPTIMAL) {
+		return nil, "No schedule found for the project"
+	}
+	model := c.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	s.ImportSolution(c.exportSchedule())
+	chain := s.CriticalChain()
+	onChain := map[string]bool{}
+	for _, id := range chain {
+		onChain[id] = true
+	}
+	// Cut every estimate down to its aggressive duration, the removed safety goes to the buffers
+	safety := map[string]int{}
+	for _, id := range c.sortedTaskIds() {
+		d := c.tasks[id].duration
+		aggressive := (d*(100-cutPercent) + 50) / 100
+		if aggressive < 1 {
+			aggressive = 1
+		}
+		safety[id] = d - aggressive
+		c.setTaskDuration(id, aggressive)
+	}
+	report := CriticalChainReport{sizingType, cutPercent, chain, []Buffer{}, c}
+	chainSafeties := []int{}
+	for _, id := range chain {
+		chainSafeties = append(chainSafeties, safety[id])
+	}
+	projectBuffer := Buffer{c.uniqueTaskId("PB"), "project", sizeBuffer(chainSafeties, sizingType), "", chain[len(chain)-1], 0, "", 0, ""}
+	preds := c.dependencyPredecessors()
+	memo := map[string][]string{}
+	for _, chainTaskId := range chain {
+		for _, feeder := range preds[chainTaskId] {
+			if onChain[feeder] || c.tasks[feeder].taskDependencies[chainTaskId] != common.FS {
+				continue
+			}
+			feedingSafeties := []int{}
+			for _, id := range c.longestFeedingPath(feeder, onChain, preds, memo) {
+				feedingSafeties = append(feedingSafeties, safety[id])
+			}
+			size := sizeBuffer(feedingSafeties, sizingType)
+			if size == 0 {
+				continue
+			}
+			id := c.uniqueTaskId(fmt.Sprintf("FB-%s-%s", feeder, chainTaskId))
+			c.AddTask(id, size)
+			delete(c.tasks[feeder].taskDependencies, chainTaskId)
+			c.AddTaskDependency(feeder, id, common.FS)
+			c.AddTaskDependency(id, chainTaskId, common.FS)
+			report.Buffers = append(report.Buffers, Buffer{id, "feeding", size, feeder, chainTaskId, 0, "", 0, ""})
+		}
+	}
+	if projectBuffer.Size > 0 {
+		c.AddTask(projectBuffer.Id, projectBuffer.Size)
+		c.AddTaskDependency(projectBuffer.ChainTaskId, projectBuffer.Id, common.FS)
+		report.Buffers = append([]Buffer{projectBuffer}, report.Buffers...)
+	}
+	if !c.Schedule(FIND_OPTIMAL) {
+		return nil, "No schedule found for the buffered project"
+	}
+	for i, b := range report.Buffers {
+		t := c.tasks[b.Id]
+		report.Buffers[i].StartT, report.Buffers[i].StartDate = t.startT, t.startDate
+		report.Buffers[i].FinishT, report.Buffers[i].FinishDate = t.finishT, t.finishDate
+	}
+	return &report, ""
+}
+
+func (r *CriticalChainReport) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<critical-chain sizing=\"%s\" cut-percent=\"%d\">\n", bufferSizingTypeToText(r.Sizing), r.CutPercent)
+	fmt.Fprintf(&w, "%s<chain>\n", xmlIndent)
+	for _, id := range r.Chain {
+		fmt.Fprintf(&w, "%s<task id=\"%s\"/>\n", strings.Repeat(xmlIndent, 2), id)
+	}
+	fmt.Fprintf(&w, "%s</chain>\n", xmlIndent)
+	fmt.Fprintf(&w, "%s<buffers>\n", xmlIndent)
+	for _, b := range r.Buffers {
+		feeding := ""
+		if b.FeedingTaskId != "" {
+			feeding = fmt.Sprintf(" feeding-task-id=\"%s\"", b.FeedingTaskId)
+		}
+		fmt.Fprintf(&w, "%s<buffer id=\"%s\" type=\"%s\" size=\"%d\"%s chain-task-id=\"%s\" start-t=\"%d\" start-date=\"%s\" finish-t=\"%d\" finish-date=\"%s\"/>\n", strings.Repeat(xmlIndent, 2), b.Id, b.Type, b.Size, feeding, b.ChainTaskId, b.StartT, b.StartDate, b.FinishT, b.FinishDate)
+	}
+	fmt.Fprintf(&w, "%s</buffers>\n", xmlIndent)
+	r.Schedule.writeScheduleXML(&w, 1)
+	fmt.Fprintf(&w, "</critical-chain>\n")
+	return w.String()
+}
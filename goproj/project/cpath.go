@@ -21,6 +21,7 @@ package project
 import (
 	"goproj/common"
 	"fmt"
+	"strings"
 )
 
 const (
@@ -34,7 +35,9 @@ type cPathNode struct {
 	ls           int
 	lf           int
 	pred         []string
+	predType     []int
 	succ         []string
+	succType     []int
 	unmarkedPred int
 	unmarkedSucc int
 	marked       bool
@@ -42,52 +45,50 @@ type cPathNode struct {
 
 type cPathNetwork map[string]cPathNode
 
+func (p *Project) cPathDuration(id string) int {
+	if id == sourceTaskId || id == sinkTaskId {
+		return 0
+	}
+	return p.tasks[id].duration
+}
+
+func (p *Project) cPathDelay(predId string, succId string, depType int) int {
+	// Minimum offset between the starts of two linked nodes, for any dependency type
+	return common.MinStartDelay(depType, p.cPathDuration(predId), p.cPathDuration(succId))
+}
+
+func linkCPathNodes(nodes cPathNetwork, predId string, succId string, depType int) {
+	aux1 := nodes[predId]
+	aux1.succ = append(aux1.succ, succId)
+	aux1.succType = append(aux1.succType, depType)
+	aux1.unmarkedSucc++
+	nodes[predId] = aux1
+	aux2 := nodes[succId]
+	aux2.pred = append(aux2.pred, predId)
+	aux2.predType = append(aux2.predType, depType)
+	aux2.unmarkedPred++
+	nodes[succId] = aux2
+}
+
 func (p *Project) buildCriticalPathNetwork() cPathNetwork {
 	nodes := cPathNetwork{}
 	for _, t := range p.tasks {
-		nodes[t.id] = cPathNode{-1, -1, -1, -1, []string{}, []string{}, 0, 0, false}
+		nodes[t.id] = cPathNode{-1, -1, -1, -1, []string{}, []int{}, []string{}, []int{}, 0, 0, false}
 	}
-	nodes[sourceTaskId] = cPathNode{-1, -1, -1, -1, []string{}, []string{}, 0, 0, false}
-	nodes[sinkTaskId] = cPathNode{-1, -1, -1, -1, []string{}, []string{}, 0, 0, false}
+	nodes[sourceTaskId] = cPathNode{-1, -1, -1, -1, []string{}, []int{}, []string{}, []int{}, 0, 0, false}
+	nodes[sinkTaskId] = cPathNode{-1, -1, -1, -1, []string{}, []int{}, []string{}, []int{}, 0, 0, false}
 	for _, t := range p.tasks {
 		for depTask, depType := range t.taskDependencies {
-			if depType != common.FS {
-				continue
-			}
-			aux1 := nodes[t.id]
-			aux1.succ = append(aux1.succ, depTask)
-			aux1.unmarkedSucc++
-			nodes[t.id] = aux1
-			aux2 := nodes[depTask]
-			aux2.pred = append(aux2.pred, t.id)
-			aux2.unmarkedPred++
-			nodes[depTask] = aux2
+			linkCPathNodes(nodes, t.id, depTask, depType)
 		}
 	}
-	for id, node := range nodes {
-		if id == sourceTaskId || id == sinkTaskId {
-			continue
-		}
-		if len(node.pred) == 0 {
-			aux1 := nodes[sourceTaskId]
-			aux1.succ = append(aux1.succ, id)
-			aux1.unmarkedSucc++
-			nodes[sourceTaskId] = aux1
-
-			node.pred = append(node.pred, sourceTaskId)
-			node.unmarkedPred = 1 // Predecessor is only the Finish Task
-			nodes[id] = node
-		}
-		if len(node.succ) == 0 {
-			node.succ = append(node.succ, sinkTaskId)
-			node.unmarkedSucc = 1 // Successor is only the Finish Task
-			nodes[id] = node
-
-			aux2 := nodes[sinkTaskId]
-			aux2.pred = append(aux2.pred, id)
-			aux2.unmarkedPred++
-			nodes[sinkTaskId] = aux2
+	for _, t := range p.tasks {
+		if len(nodes[t.id].pred) == 0 {
+			linkCPathNodes(nodes, sourceTaskId, t.id, common.FS)
 		}
+		// Unlike finish to start links, other dependency types may let a predecessor finish
+		// after its successors, so every task is bound to the Finish Task
+		linkCPathNodes(nodes, t.id, sinkTaskId, common.FS)
 	}
 	return nodes
 }
@@ -105,11 +106,12 @@ func markFromStart(nodes cPathNetwork, id string, es int, ef int) {
 	nodes[id] = aux1
 }
 
-func maxPredEarliestFinish(nodes cPathNetwork, id string) int {
+func (p *Project) maxPredDelayedStart(nodes cPathNetwork, id string) int {
 	max := 0
-	for _, pred := range nodes[id].pred {
-		if nodes[pred].ef > max {
-			max = nodes[pred].ef
+	for i, pred := range nodes[id].pred {
+		start := nodes[pred].es + p.cPathDelay(pred, id, nodes[id].predType[i])
+		if start > max {
+			max = start
 		}
 	}
 	return max
@@ -117,19 +119,31 @@ func maxPredEarliestFinish(nodes cPathNetwork, id string) int {
 
 func (p *Project) walkFromStart(nodes cPathNetwork) int {
 	markFromStart(nodes, sourceTaskId, 0, 0)
+	marked := 1
 	for {
+		progress := false
 		for id, node := range nodes {
 			if id == sourceTaskId || node.unmarkedPred > 0 || node.marked {
 				continue
 			}
-			es := maxPredEarliestFinish(nodes, id)
+			es := p.maxPredDelayedStart(nodes, id)
 			if id == sinkTaskId { // If at Finish Task
+				if marked < len(nodes)-1 {
+					// Tasks chained in a closed loop never reach the Finish Task
+					return common.UNDEF
+				}
 				return es
 			} else {
 				ef := es + p.tasks[id].duration
 				markFromStart(nodes, id, es, ef)
+				marked++
+				progress = true
 			}
 		}
+		if !progress {
+			// The remaining tasks wait on each other, dependencies must be cyclic
+			return common.UNDEF
+		}
 	}
 }
 
@@ -146,42 +160,65 @@ func markFromFinish(nodes cPathNetwork, id string, ls int, lf int) {
 	nodes[id] = aux1
 }
 
-func minSuccLatestStart(nodes cPathNetwork, id string) int {
+func (p *Project) minSuccDelayedStart(nodes cPathNetwork, id string) int {
 	min := -1
-	for _, succ := range nodes[id].succ {
-		if min == -1 || nodes[succ].ls < min {
-			min = nodes[succ].ls
+	for i, succ := range nodes[id].succ {
+		start := nodes[succ].ls - p.cPathDelay(id, succ, nodes[id].succType[i])
+		if min == -1 || start < min {
+			min = start
 		}
 	}
 	return min
 }
 
-func (p *Project) walkFromFinish(nodes cPathNetwork, makeSpan int) {
+func (p *Project) walkFromFinish(nodes cPathNetwork, makeSpan int) bool {
 	markFromFinish(nodes, sinkTaskId, makeSpan, makeSpan)
 	for {
+		progress := false
 		for id, node := range nodes {
 			if id == sinkTaskId || node.unmarkedSucc > 0 || node.marked {
 				continue
 			}
-			lf := minSuccLatestStart(nodes, id)
 			if id == sourceTaskId { // If at Start Task
-				return
+				return true
 			} else {
-				ls := lf - p.tasks[id].duration
-				markFromFinish(nodes, id, ls, lf)
+				ls := p.minSuccDelayedStart(nodes, id)
+				markFromFinish(nodes, id, ls, ls+p.tasks[id].duration)
+				progress = true
 			}
 		}
+		if !progress {
+			return false
+		}
 	}
 }
 
-func (p *Project) criticalPath() {
+func (p *Project) checkDependencyCycles() string {
+	cycle := p.findDependencyCycle()
+	if cycle != nil {
+		return fmt.Sprintf("Dependencies form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return ""
+}
+
+func (p *Project) criticalPath() cPathNetwork {
 	nodes := p.buildCriticalPathNetwork()
 	p.minMakespan = p.walkFromStart(nodes)
 	for id, node := range nodes {
 		node.marked = false
 		nodes[id] = node
 	}
-	p.walkFromFinish(nodes, p.minMakespan)
+	if p.minMakespan == common.UNDEF || !p.walkFromFinish(nodes, p.minMakespan) {
+		// Flag every task so that callers reject the inconsistent network
+		for id, task := range p.tasks {
+			task.earliestStart = common.UNDEF
+			task.earliestFinish = common.UNDEF
+			task.latestStart = common.UNDEF
+			task.latestFinish = common.UNDEF
+			p.tasks[id] = task
+		}
+		return nodes
+	}
 	for id, node := range nodes {
 		if id == sourceTaskId || id == sinkTaskId {
 			continue
@@ -193,6 +230,7 @@ func (p *Project) criticalPath() {
 		task.latestFinish = node.lf
 		p.tasks[id] = task
 	}
+	return nodes
 }
 
 // Just for debugging purposes
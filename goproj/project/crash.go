@@ -0,0 +1,292 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const crashInfinity = math.MaxInt32
+
+type crashOption struct {
+	duration int
+	unitCost int
+}
+
+type CrashAction struct {
+	Id              string
+	NormalDuration  int
+	CrashedDuration int
+	Cost            int
+}
+
+type CrashReport struct {
+	TargetMakespan int
+	Makespan       int
+	TotalCost      int
+	Reached        bool
+	Actions        []CrashAction
+	Schedule       *Project
+}
+
+func (p *Project) canCrash(id string) bool {
+	t := p.tasks[id]
+	return t.crash != nil && t.duration > t.crash.duration
+}
+
+func maxFlow(capacity [][]int, source int, sink int) int {
+	// Edmonds-Karp, capacity is left as the residual network
+	flow := 0
+	n := len(capacity)
+	for flow < crashInfinity {
+		parent := make([]int, n)
+		for i := range parent {
+			parent[i] = -1
+		}
+		parent[source] = source
+		queue := []int{source}
+		for len(queue) > 0 && parent[sink] == -1 {
+			u := queue[0]
+			queue = queue[1:]
+			for v := 0; v < n; v++ {
+				if parent[v] == -1 && capacity[u][v] > 0 {
+					parent[v] = u
+					queue = append(queue, v)
+				}
+			}
+		}
+		if parent[sink] == -1 {
+			break
+		}
+		bottleneck := crashInfinity
+		for v := sink; v != source; v = parent[v] {
+			if capacity[parent[v]][v] < bottleneck {
+				bottleneck = capacity[parent[v]][v]
+			}
+		}
+		for v := sink; v != source; v = parent[v] {
+			capacity[parent[v]][v] -= bottleneck
+			capacity[v][parent[v]] += bottleneck
+		}
+		flow += bottleneck
+	}
+	return flow
+}
+
+func (p *Project) crashCut(nodes cPathNetwork, normal *Project) ([]string, []string) {
+	// Cheapest cut of every critical path (Phillips-Dessouky), a minimum cut of the critical network
+	// where each task is split into an entry and an exit node linked by its crash cost. A task already
+	// crashed also has its cost as lower bound, so a cut crossing it backward lengthens it back
+	critical := []string{}
+	index := map[string]int{}
+	for id, node := range nodes {
+		if id == sourceTaskId || id == sinkTaskId || node.es != node.ls {
+			continue
+		}
+		index[id] = len(critical)
+		critical = append(critical, id)
+	}
+	n := len(critical)
+	source, sink := 2*n, 2*n+1
+	superSource, superSink := 2*n+2, 2*n+3
+	capacity := make([][]int, 2*n+4)
+	upper := make([][]int, 2*n+4)
+	for i := range capacity {
+		capacity[i] = make([]int, 2*n+4)
+		upper[i] = make([]int, 2*n+4)
+	}
+	for i, id := range critical {
+		node := nodes[id]
+		upper[2*i][2*i+1] = crashInfinity
+		if p.canCrash(id) {
+			upper[2*i][2*i+1] = p.tasks[id].crash.unitCost
+		}
+		if node.es == 0 {
+			upper[source][2*i] = crashInfinity
+		}
+		if node.ef == p.minMakespan {
+			upper[2*i+1][sink] = crashInfinity
+		}
+		for k, succ := range node.succ {
+			// Start linked dependencies leave the entry node and reach the successor's entry node,
+			// finish linked ones the exit nodes
+			j, isCritical := index[succ]
+			depType := node.succType[k]
+			if !isCritical || nodes[succ].es != node.es+p.cPathDelay(id, succ, depType) {
+				continue
+			}
+			from, to := 2*i+1, 2*j
+			if depType == common.SS || depType == common.SF {
+				from = 2 * i
+			}
+			if depType == common.SF || depType == common.FF {
+				to = 2*j + 1
+			}
+			upper[from][to] = crashInfinity
+		}
+	}
+	for i := range upper {
+		copy(capacity[i], upper[i])
+	}
+	// Lower bounds are met first by a flow from the super source to the exit nodes of the crashed
+	// tasks and from their entry nodes to the super sink, the sink flowing back to the source
+	required := 0
+	for i, id := range critical {
+		if p.tasks[id].duration < normal.tasks[id].duration {
+			unitCost := p.tasks[id].crash.unitCost
+			capacity[2*i][2*i+1] -= unitCost
+			capacity[superSource][2*i+1] = unitCost
+			capacity[2*i][superSink] = unitCost
+			required += unitCost
+		}
+	}
+	capacity[sink][source] = crashInfinity
+	if maxFlow(capacity, superSource, superSink) < required {
+		return nil, nil
+	}
+	capacity[sink][source], capacity[source][sink] = 0, 0
+	for v := range capacity {
+		capacity[superSource][v], capacity[v][superSource] = 0, 0
+		capacity[superSink][v], capacity[v][superSink] = 0, 0
+	}
+	maxFlow(capacity, source, sink)
+	reachable := make([]bool, 2*n+4)
+	reachable[source] = true
+	queue := []int{source}
+	for len(queue) > 0 {
+		u := queue[0]
+		queue = queue[1:]
+		for v := range capacity[u] {
+			if !reachable[v] && capacity[u][v] > 0 {
+				reachable[v] = true
+				queue = append(queue, v)
+			}
+		}
+	}
+	for u := range upper {
+		for v := range upper[u] {
+			if reachable[u] && !reachable[v] && upper[u][v] >= crashInfinity {
+				return nil, nil
+			}
+		}
+	}
+	shortened, lengthened := []string{}, []string{}
+	for i, id := range critical {
+		if reachable[2*i] && !reachable[2*i+1] {
+			shortened = append(shortened, id)
+		}
+		if !reachable[2*i] && reachable[2*i+1] && p.tasks[id].duration < normal.tasks[id].duration {
+			lengthened = append(lengthened, id)
+		}
+	}
+	return shortened, lengthened
+}
+
+func (p *Project) cheapestResourceCriticalCrash() string {
+	model := p.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	s.ImportSolution(p.exportSchedule())
+	cheapest := ""
+	for _, id := range s.CriticalTasks() {
+		if p.canCrash(id) && (cheapest == "" || p.tasks[id].crash.unitCost < p.tasks[cheapest].crash.unitCost) {
+			cheapest = id
+		}
+	}
+	return cheapest
+}
+
+func (p *Project) Crash(targetMakespan int) (*CrashReport, string) {
+	if targetMakespan <= 0 {
+		return nil, "Target makespan must be positive"
+	}
+	c := p.clone()
+	nodes := c.criticalPath()
+	for _, t := range c.tasks {
+		if t.earliestStart < 0 || t.earliestFinish < 0 {
+			return nil, "Inconsistent precedence constraints"
+		}
+	}
+	// Time-cost trade-off on the precedence network, one time unit at a time, which gives the
+	// cheapest compressions for every makespan reached
+	for c.minMakespan > targetMakespan {
+		shortened, lengthened := c.crashCut(nodes, p)
+		if len(shortened) == 0 {
+			break
+		}
+		prevMakespan := c.minMakespan
+		for _, id := range shortened {
+			c.setTaskDuration(id, c.tasks[id].duration-1)
+		}
+		for _, id := range lengthened {
+			c.setTaskDuration(id, c.tasks[id].duration+1)
+		}
+		nodes = c.criticalPath()
+		if c.minMakespan >= prevMakespan {
+			for _, id := range shortened {
+				c.setTaskDuration(id, c.tasks[id].duration+1)
+			}
+			for _, id := range lengthened {
+				c.setTaskDuration(id, c.tasks[id].duration-1)
+			}
+			break
+		}
+	}
+	// Resource contention may still stretch the schedule, so keep compressing resource critical tasks,
+	// the cheapest first. This part is a heuristic and the plan is no longer guaranteed to be the cheapest
+	for {
+		if !c.Schedule(FIND_OPTIMAL) {
+			return nil, "No schedule found for the crashed project"
+		}
+		if c.makespan <= targetMakespan {
+			break
+		}
+		id := c.cheapestResourceCriticalCrash()
+		if id == "" {
+			break
+		}
+		c.setTaskDuration(id, c.tasks[id].duration-1)
+	}
+	report := CrashReport{targetMakespan, c.makespan, 0, c.makespan <= targetMakespan, []CrashAction{}, c}
+	for _, id := range p.sortedTaskIds() {
+		normal := p.tasks[id].duration
+		crashed := c.tasks[id].duration
+		if crashed < normal {
+			cost := (normal - crashed) * p.tasks[id].crash.unitCost
+			report.Actions = append(report.Actions, CrashAction{id, normal, crashed, cost})
+			report.TotalCost += cost
+		}
+	}
+	return &report, ""
+}
+
+func (r *CrashReport) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<crash-plan target-makespan=\"%d\" makespan=\"%d\" total-cost=\"%d\" reached=\"%t\">\n", r.TargetMakespan, r.Makespan, r.TotalCost, r.Reached)
+	for _, a := range r.Actions {
+		fmt.Fprintf(&w, "%s<crash task-id=\"%s\" normal-duration=\"%d\" crashed-duration=\"%d\" cost=\"%d\"/>\n", xmlIndent, a.Id, a.NormalDuration, a.CrashedDuration, a.Cost)
+	}
+	r.Schedule.writeScheduleXML(&w, 1)
+	fmt.Fprintf(&w, "</crash-plan>\n")
+	return w.String()
+}
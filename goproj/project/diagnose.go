@@ -0,0 +1,272 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DEPENDENCY_CYCLE   = "dependency-cycle"
+	OVERSIZED_DEMAND   = "oversized-demand"
+	MAKESPAN_TOO_SHORT = "makespan-too-short"
+	RESOURCE_OVERLOAD  = "resource-overload"
+)
+
+type ConflictDependency struct {
+	TaskId          string
+	DependentTaskId string
+	Type            string
+}
+
+type Conflict struct {
+	Type         string
+	Description  string
+	Tasks        []string
+	Dependencies []ConflictDependency
+	Resources    []string
+}
+
+type Diagnosis struct {
+	Structural       bool
+	Message          string
+	FeasibleMakespan int
+	Conflicts        []Conflict
+}
+
+func (p *Project) sortedDependents(id string) []string {
+	dependents := []string{}
+	for depId := range p.tasks[id].taskDependencies {
+		dependents = append(dependents, depId)
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+func (p *Project) findDependencyCycle() []string {
+	// Depth first search keeping the current path, returns the first cycle found
+	visited := map[string]bool{}
+	onPath := map[string]int{}
+	path := []string{}
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		visited[id] = true
+		onPath[id] = len(path)
+		path = append(path, id)
+		for _, depId := range p.sortedDependents(id) {
+			pos, isOnPath := onPath[depId]
+			if isOnPath {
+				return append(append([]string{}, path[pos:]...), depId)
+			}
+			if !visited[depId] {
+				cycle := visit(depId)
+				if cycle != nil {
+					return cycle
+				}
+			}
+		}
+		delete(onPath, id)
+		path = path[:len(path)-1]
+		return nil
+	}
+	for _, id := range p.sortedTaskIds() {
+		if !visited[id] {
+			cycle := visit(id)
+			if cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Project) dependencyPath(path []string) []ConflictDependency {
+	dependencies := []ConflictDependency{}
+	for i := 0; i+1 < len(path); i++ {
+		depType := p.tasks[path[i]].taskDependencies[path[i+1]]
+		dependencies = append(dependencies, ConflictDependency{path[i], path[i+1], common.DepTypeToText(depType)})
+	}
+	return dependencies
+}
+
+func (p *Project) longestDependencyPath() (int, []string) {
+	// Earliest starts over an acyclic network honoring all dependency types, and the path defining the bound
+	es := map[string]int{}
+	pred := map[string]string{}
+	pending := map[string]int{}
+	for _, t := range p.tasks {
+		for depId := range t.taskDependencies {
+			pending[depId]++
+		}
+	}
+	queue := []string{}
+	for _, id := range p.sortedTaskIds() {
+		es[id] = 0
+		if pending[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	bound := 0
+	last := ""
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		t := p.tasks[id]
+		if es[id]+t.duration > bound || last == "" {
+			bound = es[id] + t.duration
+			last = id
+		}
+		for _, depId := range p.sortedDependents(id) {
+			start := es[id] + common.MinStartDelay(t.taskDependencies[depId], t.duration, p.tasks[depId].duration)
+			if start > es[depId] {
+				es[depId] = start
+				pred[depId] = id
+			}
+			pending[depId]--
+			if pending[depId] == 0 {
+				queue = append(queue, depId)
+			}
+		}
+	}
+	path := []string{}
+	for id := last; id != ""; id = pred[id] {
+		path = append([]string{id}, path...)
+	}
+	return bound, path
+}
+
+func (p *Project) oversizedDemands() []Conflict {
+	conflicts := []Conflict{}
+	for _, id := range p.sortedTaskIds() {
+		t := p.tasks[id]
+		for _, r := range p.sortedResourceIds() {
+			level := t.resourceAllocations[r]
+			if level > p.resources[r].capacity {
+				description := fmt.Sprintf("Task %s requires %d units of resource %s, whose capacity is %d", id, level, r, p.resources[r].capacity)
+				conflicts = append(conflicts, Conflict{OVERSIZED_DEMAND, description, []string{id}, nil, []string{r}})
+			}
+		}
+	}
+	return conflicts
+}
+
+func (p *Project) sortedResourceIds() []string {
+	resourceIds := []string{}
+	for id := range p.resources {
+		resourceIds = append(resourceIds, id)
+	}
+	sort.Strings(resourceIds)
+	return resourceIds
+}
+
+func (p *Project) resourceOverloads(makespan int) []Conflict {
+	// The fewest tasks whose workload on a resource already exceeds what it can deliver within the makespan
+	conflicts := []Conflict{}
+	for _, r := range p.sortedResourceIds() {
+		available := p.resources[r].capacity * makespan
+		taskIds := []string{}
+		for _, id := range p.sortedTaskIds() {
+			if p.tasks[id].resourceAllocations[r] > 0 {
+				taskIds = append(taskIds, id)
+			}
+		}
+		energy := func(id string) int {
+			return p.tasks[id].resourceAllocations[r] * p.tasks[id].duration
+		}
+		sort.SliceStable(taskIds, func(i, j int) bool { return energy(taskIds[i]) > energy(taskIds[j]) })
+		total := 0
+		for i, id := range taskIds {
+			total += energy(id)
+			if total > available {
+				subset := append([]string{}, taskIds[:i+1]...)
+				sort.Strings(subset)
+				description := fmt.Sprintf("Tasks require %d units of work from resource %s, which delivers at most %d within %d workdays", total, r, available, makespan)
+				conflicts = append(conflicts, Conflict{RESOURCE_OVERLOAD, description, subset, nil, []string{r}})
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+func (p *Project) Diagnose(makespan int) *Diagnosis {
+	diagnosis := Diagnosis{true, "", common.UNDEF, []Conflict{}}
+	cycle := p.findDependencyCycle()
+	if cycle != nil {
+		description := fmt.Sprintf("Dependencies form a cycle: %s", strings.Join(cycle, " -> "))
+		diagnosis.Conflicts = append(diagnosis.Conflicts, Conflict{DEPENDENCY_CYCLE, description, cycle[:len(cycle)-1], p.dependencyPath(cycle), nil})
+		diagnosis.Message = "The project is structurally infeasible"
+		return &diagnosis
+	}
+	diagnosis.Conflicts = append(diagnosis.Conflicts, p.oversizedDemands()...)
+	if makespan != FIND_OPTIMAL {
+		bound, path := p.longestDependencyPath()
+		if bound > makespan {
+			description := fmt.Sprintf("Dependencies require at least %d workdays, but the makespan is fixed to %d", bound, makespan)
+			diagnosis.Conflicts = append(diagnosis.Conflicts, Conflict{MAKESPAN_TOO_SHORT, description, path, p.dependencyPath(path), nil})
+		}
+		diagnosis.Conflicts = append(diagnosis.Conflicts, p.resourceOverloads(makespan)...)
+	}
+	if len(diagnosis.Conflicts) > 0 {
+		diagnosis.Message = "The project is structurally infeasible"
+		return &diagnosis
+	}
+	diagnosis.Structural = false
+	p.criticalPath()
+	model := p.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	feasibleMakespan, _ := s.SerialSchedule()
+	diagnosis.FeasibleMakespan = feasibleMakespan
+	if makespan == FIND_OPTIMAL || feasibleMakespan <= makespan {
+		diagnosis.Message = fmt.Sprintf("No conflicting constraints found and a schedule with makespan %d exists, the search ran out of time or iterations", feasibleMakespan)
+	} else {
+		diagnosis.Message = fmt.Sprintf("No conflicting constraints found, but the shortest schedule built needs %d workdays and feasibility within %d is unproven", feasibleMakespan, makespan)
+	}
+	return &diagnosis
+}
+
+func (d *Diagnosis) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<diagnosis structural=\"%t\"", d.Structural)
+	if d.FeasibleMakespan != common.UNDEF {
+		fmt.Fprintf(&w, " feasible-makespan=\"%d\"", d.FeasibleMakespan)
+	}
+	fmt.Fprintf(&w, ">\n")
+	fmt.Fprintf(&w, "%s<message>%s</message>\n", xmlIndent, d.Message)
+	for _, c := range d.Conflicts {
+		fmt.Fprintf(&w, "%s<conflict type=\"%s\" description=\"%s\">\n", xmlIndent, c.Type, c.Description)
+		for _, id := range c.Tasks {
+			fmt.Fprintf(&w, "%s<task id=\"%s\"/>\n", strings.Repeat(xmlIndent, 2), id)
+		}
+		for _, dep := range c.Dependencies {
+			fmt.Fprintf(&w, "%s<dependency task-id=\"%s\" dependent-task-id=\"%s\" type=\"%s\"/>\n", strings.Repeat(xmlIndent, 2), dep.TaskId, dep.DependentTaskId, dep.Type)
+		}
+		for _, id := range c.Resources {
+			fmt.Fprintf(&w, "%s<resource id=\"%s\"/>\n", strings.Repeat(xmlIndent, 2), id)
+		}
+		fmt.Fprintf(&w, "%s</conflict>\n", xmlIndent)
+	}
+	fmt.Fprintf(&w, "</diagnosis>\n")
+	return w.String()
+}
@@ -0,0 +1,92 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type StartConstraint struct {
+	Kind           string
+	PredecessorId  string
+	DependencyType string
+	ResourceId     string
+	T              int
+	Date           string
+	HeldBy         []string
+}
+
+type StartExplanation struct {
+	Id              string
+	StartT          int
+	CanStartEarlier bool
+	Constraints     []StartConstraint
+}
+
+func (p *Project) ExplainSchedule() (map[string]StartExplanation, string) {
+	if p.makespan <= 0 {
+		return nil, "Project has not been scheduled"
+	}
+	for _, t := range p.tasks {
+		if t.startT == common.UNDEF {
+			return nil, "Project has not been scheduled"
+		}
+	}
+	model := p.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	p.calendar.buildDateMap(p.makespan)
+	binding := s.ExplainSchedule(p.makespan, p.exportSchedule())
+	explanations := map[string]StartExplanation{}
+	for id, t := range p.tasks {
+		constraints := []StartConstraint{}
+		for _, b := range binding[id] {
+			if b.Kind == solver.BINDING_DEPENDENCY {
+				constraints = append(constraints, StartConstraint{"dependency", b.TaskId, common.DepTypeToText(b.DepType), "", common.UNDEF, "", nil})
+			} else {
+				sort.Strings(b.Holders)
+				constraints = append(constraints, StartConstraint{"resource", "", "", b.ResourceId, b.T, p.calendar.dateMap[b.T], b.Holders})
+			}
+		}
+		sort.SliceStable(constraints, func(i, j int) bool {
+			if constraints[i].Kind != constraints[j].Kind {
+				return constraints[i].Kind < constraints[j].Kind
+			}
+			return constraints[i].PredecessorId+constraints[i].ResourceId < constraints[j].PredecessorId+constraints[j].ResourceId
+		})
+		explanations[id] = StartExplanation{id, t.startT, t.startT > 0 && len(constraints) == 0, constraints}
+	}
+	return explanations, ""
+}
+
+func (e StartExplanation) writeXML(w *strings.Builder, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	fmt.Fprintf(w, "%s<start-constraints could-start-earlier=\"%t\">\n", indent, e.CanStartEarlier)
+	for _, c := range e.Constraints {
+		if c.Kind == "dependency" {
+			fmt.Fprintf(w, "%s<dependency predecessor-id=\"%s\" type=\"%s\"/>\n", strings.Repeat(xmlIndent, level+1), c.PredecessorId, c.DependencyType)
+		} else {
+			fmt.Fprintf(w, "%s<resource id=\"%s\" t=\"%d\" date=\"%s\" held-by=\"%s\"/>\n", strings.Repeat(xmlIndent, level+1), c.ResourceId, c.T, c.Date, strings.Join(c.HeldBy, ","))
+		}
+	}
+	fmt.Fprintf(w, "%s</start-constraints>\n", indent)
+}
@@ -0,0 +1,75 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type TaskFloat struct {
+	Id         string
+	TotalFloat int
+	FreeFloat  int
+	Critical   bool
+}
+
+type FloatReport struct {
+	Tasks         map[string]TaskFloat
+	CriticalPaths [][]string
+}
+
+func (p *Project) AnalyzeFloat() (*FloatReport, string) {
+	if p.makespan <= 0 {
+		return nil, "Project has not been scheduled"
+	}
+	for _, t := range p.tasks {
+		if t.startT == common.UNDEF {
+			return nil, "Project has not been scheduled"
+		}
+	}
+	model := p.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	analysis := s.AnalyzeFloat(p.makespan, p.exportSchedule())
+	report := FloatReport{map[string]TaskFloat{}, analysis.CriticalPaths}
+	for id := range p.tasks {
+		total := analysis.TotalFloat[id]
+		report.Tasks[id] = TaskFloat{id, total, analysis.FreeFloat[id], total == 0}
+	}
+	sort.Slice(report.CriticalPaths, func(i, j int) bool {
+		return strings.Join(report.CriticalPaths[i], " ") < strings.Join(report.CriticalPaths[j], " ")
+	})
+	return &report, ""
+}
+
+func (r *FloatReport) writeCriticalPathsXML(w *strings.Builder, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	fmt.Fprintf(w, "%s<critical-paths>\n", indent)
+	for _, path := range r.CriticalPaths {
+		fmt.Fprintf(w, "%s<critical-path>\n", strings.Repeat(xmlIndent, level+1))
+		for _, id := range path {
+			fmt.Fprintf(w, "%s<task id=\"%s\"/>\n", strings.Repeat(xmlIndent, level+2), id)
+		}
+		fmt.Fprintf(w, "%s</critical-path>\n", strings.Repeat(xmlIndent, level+1))
+	}
+	fmt.Fprintf(w, "%s</critical-paths>\n", indent)
+}
@@ -20,6 +20,7 @@ package project
 
 import (
 	"goproj/common"
+	"goproj/solver"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -30,9 +31,15 @@ import (
 
 type RootNode struct {
 	XMLName   xml.Name      `xml:"project"`
+	Algorithm string        `xml:"algorithm"`
+	Seed      *int64        `xml:"seed"`
 	Calendar  CalendarNode  `xml:"calendar"`
 	Resources ResourcesList `xml:"resources"`
 	Tasks     TasksList     `xml:"tasks"`
+	Baseline  BaselineList  `xml:"baseline"`
+	Scenarios ScenariosList `xml:"scenarios"`
+	Previous  PreviousList  `xml:"previous-schedule"`
+	Initial   InitialList   `xml:"initial-schedule"`
 }
 
 type CalendarNode struct {
@@ -71,11 +78,28 @@ type TasksList struct {
 type TaskNode struct {
 	XMLName          xml.Name         `xml:"task"`
 	Id               string           `xml:"id,attr"`
+	Milestone        bool             `xml:"milestone,attr"`
 	Duration         int              `xml:"duration"`
+	Estimate         EstimateNode     `xml:"estimate"`
+	Crash            CrashNode        `xml:"crash"`
 	DependenciesList DependenciesList `xml:"dependencies"`
 	AllocationsList  AllocationsList  `xml:"allocations"`
 }
 
+type EstimateNode struct {
+	XMLName      xml.Name `xml:"estimate"`
+	Optimistic   int      `xml:"optimistic,attr"`
+	MostLikely   int      `xml:"most-likely,attr"`
+	Pessimistic  int      `xml:"pessimistic,attr"`
+	Distribution string   `xml:"distribution,attr"`
+}
+
+type CrashNode struct {
+	XMLName  xml.Name `xml:"crash"`
+	Duration int      `xml:"duration,attr"`
+	Cost     int      `xml:"cost,attr"`
+}
+
 type DependenciesList struct {
 	XMLName    xml.Name         `xml:"dependencies"`
 	Dependency []DependencyNode `xml:"dependency"`
@@ -98,6 +122,67 @@ type AllocationNode struct {
 	Level      int      `xml:"level,attr"`
 }
 
+type BaselineList struct {
+	XMLName xml.Name           `xml:"baseline"`
+	Task    []BaselineTaskNode `xml:"task"`
+}
+
+type BaselineTaskNode struct {
+	XMLName    xml.Name `xml:"task"`
+	Id         string   `xml:"id,attr"`
+	StartT     *int     `xml:"start-t,attr"`
+	FinishT    *int     `xml:"finish-t,attr"`
+	StartDate  string   `xml:"start-date,attr"`
+	FinishDate string   `xml:"finish-date,attr"`
+}
+
+type PreviousList struct {
+	XMLName xml.Name          `xml:"previous-schedule"`
+	Task    []StartOffsetNode `xml:"task"`
+}
+
+type InitialList struct {
+	XMLName xml.Name          `xml:"initial-schedule"`
+	Task    []StartOffsetNode `xml:"task"`
+}
+
+type StartOffsetNode struct {
+	XMLName   xml.Name `xml:"task"`
+	Id        string   `xml:"id,attr"`
+	StartT    *int     `xml:"start-t,attr"`
+	StartDate string   `xml:"start-date,attr"`
+}
+
+type ScenariosList struct {
+	XMLName  xml.Name       `xml:"scenarios"`
+	Scenario []ScenarioNode `xml:"scenario"`
+}
+
+type ScenarioNode struct {
+	XMLName          xml.Name                 `xml:"scenario"`
+	Name             string                   `xml:"name,attr"`
+	Duration         []ScenarioDurationNode   `xml:"duration"`
+	Capacity         []ScenarioCapacityNode   `xml:"capacity"`
+	AddDependency    []ScenarioDependencyNode `xml:"add-dependency"`
+	RemoveDependency []ScenarioDependencyNode `xml:"remove-dependency"`
+}
+
+type ScenarioDurationNode struct {
+	TaskId string `xml:"task-id,attr"`
+	Value  int    `xml:"value,attr"`
+}
+
+type ScenarioCapacityNode struct {
+	ResourceId string `xml:"resource-id,attr"`
+	Value      int    `xml:"value,attr"`
+}
+
+type ScenarioDependencyNode struct {
+	TaskId          string `xml:"task-id,attr"`
+	DependentTaskId string `xml:"dependent-task-id,attr"`
+	Type            string `xml:"type,attr"`
+}
+
 var WeekDayNameToIndex = map[string]int{"sunday": 0, "monday": 1, "tuesday": 2, "wednesday": 3, "thursday": 4, "friday": 5, "saturday": 6}
 var WeekDayNameToIndexAbrev = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
 
@@ -150,16 +235,36 @@ func (p *Project) importResources(xmlTree *RootNode) string {
 func (p *Project) importTasks(xmlTree *RootNode) string {
 	taskDependencies := map[string]map[string]int{}
 	for _, t := range xmlTree.Tasks.Task {
-		if t.Id == "" || t.Duration == 0 {
+		duration := t.Duration
+		if duration == 0 {
+			// A three-point estimate alone is enough, its most likely value is the planned duration
+			duration = t.Estimate.MostLikely
+		}
+		if t.Id == "" || duration == 0 {
 			return fmt.Sprintf("A task tag is missing one or more attributes")
 		}
-		if t.Duration < 0 {
+		if duration < 0 {
 			return fmt.Sprintf("Task '%s' has zero or negative duration", t.Id)
 		}
-		err := p.AddTask(t.Id, t.Duration)
+		err := p.AddTask(t.Id, duration)
 		if err != "" {
 			return err
 		}
+		if t.Estimate.Optimistic != 0 || t.Estimate.MostLikely != 0 || t.Estimate.Pessimistic != 0 {
+			err := p.AddTaskEstimate(t.Id, t.Estimate.Optimistic, t.Estimate.MostLikely, t.Estimate.Pessimistic, t.Estimate.Distribution)
+			if err != "" {
+				return err
+			}
+		}
+		if t.Milestone {
+			p.SetMilestone(t.Id)
+		}
+		if t.Crash.Duration != 0 {
+			err := p.AddTaskCrashOption(t.Id, t.Crash.Duration, t.Crash.Cost)
+			if err != "" {
+				return err
+			}
+		}
 		for _, dep := range t.DependenciesList.Dependency {
 			depType := common.FS
 			if dep.DependentTaskId == "" {
@@ -199,6 +304,87 @@ func (p *Project) importTasks(xmlTree *RootNode) string {
 	return ""
 }
 
+func (p *Project) importBaselineOffset(t *int, date string, taskId string) (int, string) {
+	if t != nil {
+		return *t, ""
+	}
+	if date != "" {
+		return p.calendar.dateToOffset(date)
+	}
+	return common.UNDEF, fmt.Sprintf("Baseline of task '%s' is missing one or more attributes", taskId)
+}
+
+func (p *Project) importBaseline(xmlTree *RootNode) string {
+	for _, b := range xmlTree.Baseline.Task {
+		if b.Id == "" {
+			return fmt.Sprintf("A baseline task tag is missing one or more attributes")
+		}
+		startT, err := p.importBaselineOffset(b.StartT, b.StartDate, b.Id)
+		if err != "" {
+			return err
+		}
+		finishT, err := p.importBaselineOffset(b.FinishT, b.FinishDate, b.Id)
+		if err != "" {
+			return err
+		}
+		err = p.SetBaselineTask(b.Id, startT, finishT)
+		if err != "" {
+			return err
+		}
+	}
+	return ""
+}
+
+func (p *Project) importStartOffsets(nodes []StartOffsetNode, section string, setStart func(string, int) string) string {
+	for _, node := range nodes {
+		if node.Id == "" || (node.StartT == nil && node.StartDate == "") {
+			return fmt.Sprintf("A %s task tag is missing one or more attributes", section)
+		}
+		startT := common.UNDEF
+		if node.StartT != nil {
+			startT = *node.StartT
+		} else {
+			var err string
+			startT, err = p.calendar.dateToOffset(node.StartDate)
+			if err != "" {
+				return err
+			}
+		}
+		err := setStart(node.Id, startT)
+		if err != "" {
+			return err
+		}
+	}
+	return ""
+}
+
+func (p *Project) importScenarios(xmlTree *RootNode) string {
+	for _, node := range xmlTree.Scenarios.Scenario {
+		sc := NewScenario(node.Name)
+		for _, d := range node.Duration {
+			sc.SetDuration(d.TaskId, d.Value)
+		}
+		for _, c := range node.Capacity {
+			sc.SetCapacity(c.ResourceId, c.Value)
+		}
+		for _, dep := range node.AddDependency {
+			depType := common.FS
+			if dep.Type != "" {
+				depType = common.DepTextToType(dep.Type)
+			}
+			sc.AddDependency(dep.TaskId, dep.DependentTaskId, depType)
+		}
+		for _, dep := range node.RemoveDependency {
+			sc.RemoveDependency(dep.TaskId, dep.DependentTaskId)
+		}
+		err := p.AddScenario(sc)
+		if err != "" {
+			return err
+		}
+	}
+	return ""
+}
+
 func importFromXmlRawBytes(xmlRawBytes []byte) (*Project, string) {
 	var xmlTree RootNode
 	err := xml.Unmarshal(xmlRawBytes, &xmlTree)
@@ -210,6 +396,15 @@ func importFromXmlRawBytes(xmlRawBytes []byte) (*Project, string) {
 	if errStr != "" {
 		return nil, errStr
 	}
+	if xmlTree.Algorithm != "" {
+		errStr = p.SetAlgorithm(xmlTree.Algorithm)
+		if errStr != "" {
+			return nil, errStr
+		}
+	}
+	if xmlTree.Seed != nil {
+		p.SetSeed(*xmlTree.Seed)
+	}
 	errStr = p.importResources(&xmlTree)
 	if errStr != "" {
 		return nil, errStr
@@ -218,6 +413,26 @@ func importFromXmlRawBytes(xmlRawBytes []byte) (*Project, string) {
 	if errStr != "" {
 		return nil, errStr
 	}
+	errStr = p.checkDependencyCycles()
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importBaseline(&xmlTree)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importScenarios(&xmlTree)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importStartOffsets(xmlTree.Previous.Task, "previous schedule", p.SetPreviousStart)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importStartOffsets(xmlTree.Initial.Task, "initial schedule", p.SetInitialStart)
+	if errStr != "" {
+		return nil, errStr
+	}
 	return p, ""
 }
 
@@ -241,6 +456,15 @@ func ImportFromDirectXMLTree(xmlTree RootNode) (*Project, string) {
 	if errStr != "" {
 		return nil, errStr
 	}
+	if xmlTree.Algorithm != "" {
+		errStr = p.SetAlgorithm(xmlTree.Algorithm)
+		if errStr != "" {
+			return nil, errStr
+		}
+	}
+	if xmlTree.Seed != nil {
+		p.SetSeed(*xmlTree.Seed)
+	}
 	errStr = p.importResources(&xmlTree)
 	if errStr != "" {
 		return nil, errStr
@@ -249,12 +473,38 @@ func ImportFromDirectXMLTree(xmlTree RootNode) (*Project, string) {
 	if errStr != "" {
 		return nil, errStr
 	}
+	errStr = p.checkDependencyCycles()
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importBaseline(&xmlTree)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importScenarios(&xmlTree)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importStartOffsets(xmlTree.Previous.Task, "previous schedule", p.SetPreviousStart)
+	if errStr != "" {
+		return nil, errStr
+	}
+	errStr = p.importStartOffsets(xmlTree.Initial.Task, "initial schedule", p.SetInitialStart)
+	if errStr != "" {
+		return nil, errStr
+	}
 	return p, ""
 }
 
 func (project *Project) ExportToXML(w io.Writer) {
 	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
 	fmt.Fprintf(w, "<project>\n")
+	if project.parameters.algorithm != solver.DEFAULT_ALGORITHM {
+		fmt.Fprintf(w, "%s<algorithm>%s</algorithm>\n", xmlIndent, project.parameters.algorithm)
+	}
+	if project.parameters.seeded {
+		fmt.Fprintf(w, "%s<seed>%d</seed>\n", xmlIndent, project.parameters.seed)
+	}
 	if project.makespan > 0 {
 		fmt.Fprintf(w, "%s<makespan>%d</makespan>\n", xmlIndent, project.makespan)
 	}
@@ -265,8 +515,18 @@ func (project *Project) ExportToXML(w io.Writer) {
 	fmt.Fprintf(w, "%s</resources>\n", xmlIndent)
 	fmt.Fprintf(w, "%s<tasks>\n", xmlIndent)
 	for _, t := range project.tasks {
-		fmt.Fprintf(w, "%s<task id=\"%s\">\n", strings.Repeat(xmlIndent, 2), t.id)
+		if t.milestone {
+			fmt.Fprintf(w, "%s<task id=\"%s\" milestone=\"true\">\n", strings.Repeat(xmlIndent, 2), t.id)
+		} else {
+			fmt.Fprintf(w, "%s<task id=\"%s\">\n", strings.Repeat(xmlIndent, 2), t.id)
+		}
 		fmt.Fprintf(w, "%s<duration>%d</duration>\n", strings.Repeat(xmlIndent, 3), t.duration)
+		if t.estimate != nil {
+			fmt.Fprintf(w, "%s<estimate optimistic=\"%d\" most-likely=\"%d\" pessimistic=\"%d\" distribution=\"%s\"/>\n", strings.Repeat(xmlIndent, 3), t.estimate.optimistic, t.estimate.mostLikely, t.estimate.pessimistic, distTypeToText(t.estimate.distribution))
+		}
+		if t.crash != nil {
+			fmt.Fprintf(w, "%s<crash duration=\"%d\" cost=\"%d\"/>\n", strings.Repeat(xmlIndent, 3), t.crash.duration, t.crash.unitCost)
+		}
 		if t.startT > common.UNDEF {
 			fmt.Fprintf(w, "%s<start-t>%d</start-t>\n", strings.Repeat(xmlIndent, 3), t.startT)
 		}
@@ -300,30 +560,94 @@ func (project *Project) ExportToXML(w io.Writer) {
 		fmt.Fprintf(w, "%s</task>\n", strings.Repeat(xmlIndent, 2))
 	}
 	fmt.Fprintf(w, "%s</tasks>\n", xmlIndent)
+	if project.baseline != nil {
+		fmt.Fprintf(w, "%s<baseline>\n", xmlIndent)
+		for id, entry := range project.baseline.tasks {
+			fmt.Fprintf(w, "%s<task id=\"%s\" start-t=\"%d\" finish-t=\"%d\"/>\n", strings.Repeat(xmlIndent, 2), id, entry.startT, entry.finishT)
+		}
+		fmt.Fprintf(w, "%s</baseline>\n", xmlIndent)
+	}
+	if project.previous != nil {
+		fmt.Fprintf(w, "%s<previous-schedule>\n", xmlIndent)
+		for id, startT := range project.previous {
+			fmt.Fprintf(w, "%s<task id=\"%s\" start-t=\"%d\"/>\n", strings.Repeat(xmlIndent, 2), id, startT)
+		}
+		fmt.Fprintf(w, "%s</previous-schedule>\n", xmlIndent)
+	}
+	if project.initial != nil {
+		fmt.Fprintf(w, "%s<initial-schedule>\n", xmlIndent)
+		for id, startT := range project.initial {
+			fmt.Fprintf(w, "%s<task id=\"%s\" start-t=\"%d\"/>\n", strings.Repeat(xmlIndent, 2), id, startT)
+		}
+		fmt.Fprintf(w, "%s</initial-schedule>\n", xmlIndent)
+	}
 	fmt.Fprintf(w, "</project>\n")
 }
 
-func (project *Project) ExportScheduleToStringXML() string {
-	var w strings.Builder
-	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
-	fmt.Fprintf(&w, "<schedule makespan=\"%d\">\n", project.makespan)
-	for _, t := range project.tasks {
-		fmt.Fprintf(&w, "%s<task id=\"%s\">\n", xmlIndent, t.id)
-		fmt.Fprintf(&w, "%s<duration>%d</duration>\n", strings.Repeat(xmlIndent, 2), t.duration)
+func (project *Project) writeScheduleXML(w *strings.Builder, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	if project.lowerBound > common.UNDEF {
+		fmt.Fprintf(w, "%s<schedule makespan=\"%d\" lower-bound=\"%d\" gap=\"%d\" proven-optimal=\"%t\">\n", indent, project.makespan, project.lowerBound, project.makespan-project.lowerBound, project.proven)
+	} else {
+		fmt.Fprintf(w, "%s<schedule makespan=\"%d\">\n", indent, project.makespan)
+	}
+	comparison, _ := project.CompareToBaseline()
+	variances := map[string]TaskVariance{}
+	if comparison != nil {
+		variances = comparison.varianceByTask()
+	}
+	explanations, _ := project.ExplainSchedule()
+	floats, _ := project.AnalyzeFloat()
+	usage, _ := project.AnalyzeResourceUsage()
+	for _, id := range project.sortedTaskIds() {
+		t := project.tasks[id]
+		fmt.Fprintf(w, "%s<task id=\"%s\">\n", strings.Repeat(xmlIndent, level+1), t.id)
+		fmt.Fprintf(w, "%s<duration>%d</duration>\n", strings.Repeat(xmlIndent, level+2), t.duration)
 		if t.startT > common.UNDEF {
-			fmt.Fprintf(&w, "%s<start-t>%d</start-t>\n", strings.Repeat(xmlIndent, 2), t.startT)
+			fmt.Fprintf(w, "%s<start-t>%d</start-t>\n", strings.Repeat(xmlIndent, level+2), t.startT)
 		}
 		if t.startDate != "" {
-			fmt.Fprintf(&w, "%s<start-date>%s</start-date>\n", strings.Repeat(xmlIndent, 2), t.startDate)
+			fmt.Fprintf(w, "%s<start-date>%s</start-date>\n", strings.Repeat(xmlIndent, level+2), t.startDate)
 		}
 		if t.finishT > common.UNDEF {
-			fmt.Fprintf(&w, "%s<finish-t>%d</finish-t>\n", strings.Repeat(xmlIndent, 2), t.finishT)
+			fmt.Fprintf(w, "%s<finish-t>%d</finish-t>\n", strings.Repeat(xmlIndent, level+2), t.finishT)
 		}
 		if t.finishDate != "" {
-			fmt.Fprintf(&w, "%s<finish-date>%s</finish-date>\n", strings.Repeat(xmlIndent, 2), t.finishDate)
+			fmt.Fprintf(w, "%s<finish-date>%s</finish-date>\n", strings.Repeat(xmlIndent, level+2), t.finishDate)
+		}
+		v, hasBaseline := variances[t.id]
+		if hasBaseline {
+			fmt.Fprintf(w, "%s<variance baseline-start-t=\"%d\" baseline-finish-t=\"%d\" start=\"%d\" finish=\"%d\"/>\n", strings.Repeat(xmlIndent, level+2), v.BaselineStartT, v.BaselineFinishT, v.StartVariance, v.FinishVariance)
+		}
+		previousStartT, hasPrevious := project.previous[t.id]
+		if hasPrevious && t.startT > common.UNDEF {
+			fmt.Fprintf(w, "%s<previous-start start-t=\"%d\" shift=\"%d\"/>\n", strings.Repeat(xmlIndent, level+2), previousStartT, t.startT-previousStartT)
+		}
+		if floats != nil {
+			f := floats.Tasks[t.id]
+			fmt.Fprintf(w, "%s<float total=\"%d\" free=\"%d\" is-critical=\"%t\"/>\n", strings.Repeat(xmlIndent, level+2), f.TotalFloat, f.FreeFloat, f.Critical)
 		}
-		fmt.Fprintf(&w, "%s</task>\n", xmlIndent)
+		e, hasExplanation := explanations[t.id]
+		if hasExplanation {
+			e.writeXML(w, level+2)
+		}
+		fmt.Fprintf(w, "%s</task>\n", strings.Repeat(xmlIndent, level+1))
+	}
+	if floats != nil {
+		floats.writeCriticalPathsXML(w, level+1)
+	}
+	if usage != nil {
+		usage.writeXML(w, level+1)
 	}
-	fmt.Fprintf(&w, "</schedule>\n")
+	if comparison != nil {
+		comparison.writeSummaryXML(w, level+1)
+	}
+	fmt.Fprintf(w, "%s</schedule>\n", indent)
+}
+
+func (project *Project) ExportScheduleToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	project.writeScheduleXML(&w, 0)
 	return w.String()
 }
@@ -21,6 +21,7 @@ package project
 import (
 	"goproj/common"
 	"goproj/solver"
+	"context"
 	"fmt"
 )
 
@@ -47,6 +48,9 @@ type task struct {
 	earliestFinish      int
 	latestStart         int
 	latestFinish        int
+	estimate            *durationEstimate
+	crash               *crashOption
+	milestone           bool
 }
 
 type solverParameters struct {
@@ -54,6 +58,11 @@ type solverParameters struct {
 	threads       int
 	step          int
 	maxTime       int
+	algorithm     string
+	members       []solver.PortfolioMember
+	portfolioMode string
+	seed          int64
+	seeded        bool
 }
 
 type Project struct {
@@ -63,6 +72,13 @@ type Project struct {
 	minMakespan int
 	parameters  solverParameters
 	calendar    calendar
+	baseline    *baseline
+	scenarios   []*Scenario
+	previous    common.TaskSchedule
+	initial     common.TaskSchedule
+	lowerBound  int
+	proven      bool
+	progress    solver.ProgressFunc
 }
 
 func (t task) SetT(time int) task {
@@ -72,9 +88,9 @@ func (t task) SetT(time int) task {
 }
 
 func NewProject() *Project {
-	param := solverParameters{solver.DEFAULT_MAX_ITERATIONS, solver.DEFAULT_THREADS, solver.DEFAULT_STEP, 0}
+	param := solverParameters{solver.DEFAULT_MAX_ITERATIONS, solver.DEFAULT_THREADS, solver.DEFAULT_STEP, 0, solver.DEFAULT_ALGORITHM, nil, "", 0, false}
 	c := NewCalendar()
-	p := Project{map[string]task{}, map[string]resource{}, common.UNDEF, common.UNDEF, param, *c}
+	p := Project{map[string]task{}, map[string]resource{}, common.UNDEF, common.UNDEF, param, *c, nil, []*Scenario{}, nil, nil, common.UNDEF, false, nil}
 	return &p
 }
 
@@ -104,7 +120,7 @@ func (project *Project) AddTask(id string, duration int) string {
 	if duplicate {
 		return fmt.Sprintf("Duplicate task '%s'", id)
 	} else {
-		project.tasks[id] = task{id, duration, common.UNDEF, "", common.UNDEF, "", map[string]int{}, map[string]int{}, common.UNDEF, common.UNDEF, common.UNDEF, common.UNDEF}
+		project.tasks[id] = task{id, duration, common.UNDEF, "", common.UNDEF, "", map[string]int{}, map[string]int{}, common.UNDEF, common.UNDEF, common.UNDEF, common.UNDEF, nil, nil, false}
 		return ""
 	}
 }
@@ -133,6 +149,39 @@ func (project *Project) AddTaskDependency(firstTaskId string, secondTaskId strin
 	return ""
 }
 
+func (project *Project) AddTaskEstimate(taskId string, optimistic int, mostLikely int, pessimistic int, distribution string) string {
+	t, exists := project.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	if optimistic <= 0 || optimistic > mostLikely || mostLikely > pessimistic {
+		return fmt.Sprintf("Task '%s' estimate must satisfy 0 < optimistic <= most likely <= pessimistic", taskId)
+	}
+	distType := distTextToType(distribution)
+	if distType == common.UNDEF {
+		return fmt.Sprintf("Unknown distribution '%s' for task '%s'", distribution, taskId)
+	}
+	t.estimate = &durationEstimate{optimistic, mostLikely, pessimistic, distType}
+	project.tasks[taskId] = t
+	return ""
+}
+
+func (project *Project) AddTaskCrashOption(taskId string, crashDuration int, unitCost int) string {
+	t, exists := project.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	if crashDuration <= 0 || crashDuration > t.duration {
+		return fmt.Sprintf("Task '%s' crash duration must be positive and not above its normal duration", taskId)
+	}
+	if unitCost < 0 {
+		return fmt.Sprintf("Task '%s' has negative crash cost", taskId)
+	}
+	t.crash = &crashOption{crashDuration, unitCost}
+	project.tasks[taskId] = t
+	return ""
+}
+
 func (project *Project) AddResourceAllocation(taskId string, resourceId string, level int) string {
 	_, existsTask := project.tasks[taskId]
 	if !existsTask {
@@ -156,12 +205,50 @@ func (project *Project) AddResourceAllocation(taskId string, resourceId string,
 	return ""
 }
 
+func (p *Project) clone() *Project {
+	c := *p
+	c.tasks = map[string]task{}
+	for id, t := range p.tasks {
+		allocations := map[string]int{}
+		for resourceId, level := range t.resourceAllocations {
+			allocations[resourceId] = level
+		}
+		dependencies := map[string]int{}
+		for taskId, depType := range t.taskDependencies {
+			dependencies[taskId] = depType
+		}
+		t.resourceAllocations = allocations
+		t.taskDependencies = dependencies
+		c.tasks[id] = t
+	}
+	c.resources = map[string]resource{}
+	for id, r := range p.resources {
+		c.resources[id] = r
+	}
+	c.progress = nil // Analyses schedule their clones silently
+	return &c
+}
+
 func (p *Project) importSchedule(schedule common.TaskSchedule) {
 	for id, time := range schedule {
 		p.tasks[id] = p.tasks[id].SetT(time)
 	}
 }
 
+func (p *Project) exportSchedule() common.TaskSchedule {
+	schedule := common.TaskSchedule{}
+	for id, t := range p.tasks {
+		schedule[id] = t.startT
+	}
+	return schedule
+}
+
+func (p *Project) setTaskDuration(id string, duration int) {
+	t := p.tasks[id]
+	t.duration = duration
+	p.tasks[id] = t
+}
+
 func (p *Project) checkTaskDependencies(t task) string {
 	msg := ""
 	for depTaskId, depType := range t.taskDependencies {
@@ -267,8 +354,16 @@ func (p *Project) convertTimeOffsetsToDate() {
 }
 
 func (p *Project) Schedule(makespan int) bool {
+	return p.ScheduleContext(context.Background(), makespan)
+}
+
+func (p *Project) ScheduleContext(ctx context.Context, makespan int) bool {
+	// A cancelled context or an expired deadline ends the search with the best schedule found so far
 	var res int
 	var sched common.TaskSchedule
+	if p.checkDependencyCycles() != "" {
+		return false
+	}
 	p.criticalPath()
 	for _, t := range p.tasks {
 		// Validate against inconsistent precedence constraints that mess up critical path results
@@ -277,18 +372,46 @@ func (p *Project) Schedule(makespan int) bool {
 		}
 	}
 	model := p.buildConstraintModel()
-	s := solver.NewSolver(*model)
+	s, err := solver.NewAlgorithm(p.parameters.algorithm, *model)
+	if err != "" {
+		return false
+	}
 	s.SetParameters(p.parameters.maxIterations, p.parameters.threads, p.parameters.step, p.parameters.maxTime)
+	seeded, isSeeded := s.(solver.SeededAlgorithm)
+	if isSeeded && p.parameters.seeded {
+		seeded.SetSeed(p.parameters.seed)
+	}
+	portfolio, isPortfolio := s.(*solver.PortfolioSolver)
+	if isPortfolio && portfolio.SetMembers(p.parameters.members, p.parameters.portfolioMode) != "" {
+		return false
+	}
+	progressive, isProgressive := s.(solver.ProgressAlgorithm)
+	if isProgressive && p.progress != nil {
+		progressive.SetProgress(p.progress)
+	}
+	if p.previous != nil {
+		s.SetReference(p.previous)
+	}
+	if p.initial != nil {
+		s.SetInitialAssignment(p.initial)
+	}
 	if makespan == FIND_OPTIMAL {
-		res, sched = s.SolveOptimalMakespan()
+		res, sched = solver.SolveOptimalMakespanContext(ctx, s)
 	} else {
-		sched = s.SolveFixedMakespan(makespan)
+		sched = solver.SolveFixedMakespanContext(ctx, s, makespan)
 		if sched == nil {
 			res = 0
 		} else {
 			res = makespan
 		}
 	}
+	p.lowerBound, p.proven = common.UNDEF, false
+	bounded, isBounded := s.(solver.BoundedAlgorithm)
+	if isBounded {
+		// A schedule meeting the lower bound is optimal whatever the algorithm
+		p.lowerBound = bounded.LowerBound()
+		p.proven = bounded.Proven() || (res > 0 && res == p.lowerBound)
+	}
 	if res > 0 {
 		p.importSchedule(sched)
 		p.makespan = res
@@ -299,11 +422,107 @@ func (p *Project) Schedule(makespan int) bool {
 	}
 }
 
+func (p *Project) ScheduleLadder(ctx context.Context, times []int, iterations []int) bool {
+	// Every limit of the ladder in turn until a schedule is found, the iterations for a seeded search and the times otherwise
+	ladder := times
+	if p.parameters.seeded {
+		ladder = iterations
+	}
+	for _, limit := range ladder {
+		p.SetSolverLimit(limit, limit)
+		if p.ScheduleContext(ctx, FIND_OPTIMAL) {
+			return true
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return false
+}
+
+func (p *Project) SetPreviousStart(taskId string, startT int) string {
+	// Schedules are then kept as close as possible to the previous start offsets
+	_, exists := p.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	if startT < 0 {
+		return fmt.Sprintf("Task '%s' has a negative previous start", taskId)
+	}
+	if p.previous == nil {
+		p.previous = common.TaskSchedule{}
+	}
+	p.previous[taskId] = startT
+	return ""
+}
+
+func (p *Project) SetInitialStart(taskId string, startT int) string {
+	// Warm start of the search, typically a schedule obtained before a small edit
+	_, exists := p.tasks[taskId]
+	if !exists {
+		return fmt.Sprintf("Undefined task '%s'", taskId)
+	}
+	if startT < 0 {
+		return fmt.Sprintf("Task '%s' has a negative initial start", taskId)
+	}
+	if p.initial == nil {
+		p.initial = common.TaskSchedule{}
+	}
+	p.initial[taskId] = startT
+	return ""
+}
+
+func (p *Project) GetLowerBound() int {
+	return p.lowerBound
+}
+
+func (p *Project) IsProvenOptimal() bool {
+	return p.proven
+}
+
 func (p *Project) GetMinMakespan() int {
 	p.criticalPath()
 	return p.minMakespan
 }
 
 func (p *Project) SetSolverParameters(maxIterations int, threads int, step int, maxTime int) {
-	p.parameters = solverParameters{maxIterations, threads, step, maxTime}
+	p.parameters = solverParameters{maxIterations, threads, step, maxTime, p.parameters.algorithm, p.parameters.members, p.parameters.portfolioMode, p.parameters.seed, p.parameters.seeded}
+}
+
+func (p *Project) SetSolverLimit(maxTime int, maxIterations int) {
+	// A seeded search stops on its iterations alone, so that machine load plays no part in where it stops
+	if p.parameters.seeded {
+		p.parameters.maxIterations, p.parameters.maxTime = maxIterations, 0
+	} else {
+		p.parameters.maxIterations, p.parameters.maxTime = 0, maxTime
+	}
+}
+
+func (p *Project) SetAlgorithm(name string) string {
+	if !solver.HasAlgorithm(name) {
+		return fmt.Sprintf("Unknown solver algorithm '%s'", name)
+	}
+	p.parameters.algorithm = name
+	return ""
+}
+
+func (p *Project) SetSeed(seed int64) {
+	// Scheduling and simulation then repeat their results, as long as no time limit cuts the search short
+	p.parameters.seed, p.parameters.seeded = seed, true
+}
+
+func (p *Project) SetProgress(callback solver.ProgressFunc) {
+	// Called with every improved schedule found while scheduling, nil stops the reports
+	p.progress = callback
+}
+
+func (p *Project) SetPortfolio(members []solver.PortfolioMember, mode string) string {
+	// Members run side by side when the portfolio algorithm is selected
+	err := solver.CheckPortfolio(members, mode, p.parameters.seeded && p.parameters.algorithm == solver.PORTFOLIO_ALGORITHM)
+	if err != "" {
+		return err
+	}
+	p.parameters.members = members
+	p.parameters.portfolioMode = mode
+	return ""
 }
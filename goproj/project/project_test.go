@@ -20,12 +20,15 @@ package project
 
 import (
 	"bufio"
+	"context"
 	"goproj/common"
 	"goproj/solver"
 	"fmt"
+	"math/rand"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -102,6 +105,41 @@ func RunRcpSuite(instancesFilename string, reportFilename string) {
 	}
 }
 
+func RunAlgorithmBenchmark(instancesFilename string, reportFilename string, algorithms []string) {
+	instancesFile, err := os.Open(instancesFilename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: file '%s' not found\n", instancesFilename)
+		return
+	}
+	defer instancesFile.Close()
+	reportFile, err := os.Create(reportFilename)
+	defer reportFile.Close()
+	fmt.Fprintf(reportFile, "instance,best-known,algorithm,makespan,deviation,elapsed\n")
+	scanner := bufio.NewScanner(instancesFile)
+	for scanner.Scan() {
+		line := scanner.Text()
+		r, _ := regexp.Compile("(.*),([0-9]+)$")
+		tokens := r.FindStringSubmatch(line)
+		filename := tokens[1]
+		bestKnown, _ := strconv.Atoi(tokens[2])
+		for _, algorithm := range algorithms {
+			p, err := LoadPspLibRcpFile(filename)
+			if err != "" {
+				fmt.Fprintf(reportFile, "%s,%d,%s,Exception - %s\n", filename, bestKnown, algorithm, err)
+				continue
+			}
+			p.SetAlgorithm(algorithm)
+			start := time.Now()
+			if !p.Schedule(FIND_OPTIMAL) || p.CheckScheduleConsistency() != "" {
+				fmt.Fprintf(reportFile, "%s,%d,%s,No schedule found\n", filename, bestKnown, algorithm)
+				continue
+			}
+			elapsed := time.Since(start)
+			fmt.Fprintf(reportFile, "%s,%d,%s,%d,%d,%s\n", filename, bestKnown, algorithm, p.makespan, p.makespan-bestKnown, elapsed)
+		}
+	}
+}
+
 func TestExample(t *testing.T) {
 	if true {
 		return
@@ -187,6 +225,20 @@ func TestRcpSuites(t *testing.T) {
 	}
 }
 
+func TestAlgorithmBenchmark(t *testing.T) {
+	if !testRcpSuites {
+		return
+	}
+	for i := 30; i <= 60; i += 30 {
+		testname := fmt.Sprintf("Benchmarking J%d instances", i)
+		t.Run(testname, func(t *testing.T) {
+			suitFilename := fmt.Sprintf("instances_j%d_w.csv", i)
+			reportFilename := fmt.Sprintf("benchmark_j%d.csv", i)
+			RunAlgorithmBenchmark(suitFilename, reportFilename, []string{solver.DEFAULT_ALGORITHM, solver.GENETIC_ALGORITHM})
+		})
+	}
+}
+
 func TestAllSequentialTasksFixed(t *testing.T) {
 	for n := 1; n <= 30; n++ {
 		testname := fmt.Sprintf("Testing %d sequential tasks", n)
@@ -521,6 +573,1022 @@ func TestCompactSequentialTasksVar(t *testing.T) {
 	}
 }
 
+func TestSerialScheduleMixedDependencies(t *testing.T) {
+	depTypes := []int{common.SS, common.SF, common.FS, common.FF}
+	for n := 2; n <= 20; n++ {
+		testname := fmt.Sprintf("Testing %d tasks", n)
+		t.Run(testname, func(t *testing.T) {
+			proj := NewProject()
+			proj.AddResource("R1", 2)
+			for i := 1; i <= n; i++ {
+				id := fmt.Sprintf("T%04d", i)
+				proj.AddTask(id, 1+i%4)
+				proj.AddResourceAllocation(id, "R1", 1+i%2)
+			}
+			for i := 1; i <= n-1; i++ {
+				proj.AddTaskDependency(fmt.Sprintf("T%04d", i), fmt.Sprintf("T%04d", i+1), depTypes[i%4])
+			}
+			proj.criticalPath()
+			model := proj.buildConstraintModel()
+			s := solver.NewSolver(*model)
+			makespan, sched := s.SerialSchedule()
+			if sched == nil {
+				t.Fatalf("No serial schedule for n=%d", n)
+			}
+			proj.importSchedule(sched)
+			proj.makespan = makespan
+			err := proj.CheckScheduleConsistency()
+			if err != "" {
+				t.Errorf("Inconsistent serial schedule for n=%d - %s", n, err)
+			}
+		})
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	proj := NewProject()
+	proj.AddTask("T1", 5)
+	proj.AddTask("T2", 4)
+	proj.AddTask("T3", 3)
+	proj.AddTaskEstimate("T1", 3, 5, 10, "triangular")
+	proj.AddTaskEstimate("T2", 2, 4, 9, "pert")
+	proj.AddTaskDependency("T1", "T2", common.FS)
+	proj.AddTaskDependency("T2", "T3", common.FS)
+	report, err := proj.Simulate(500)
+	if err != "" {
+		t.Fatalf("Simulation failed - %s", err)
+	}
+	if report.Completion.Min < 3+2+3 || report.Completion.Max > 10+9+3 {
+		t.Errorf("Completion range [%d, %d] outside of estimates", report.Completion.Min, report.Completion.Max)
+	}
+	prev := 0
+	for _, pct := range report.Completion.Percentiles {
+		if pct.T < prev || pct.Date == "" {
+			t.Errorf("Bad percentile P%d = %d (%s)", pct.Level, pct.T, pct.Date)
+		}
+		prev = pct.T
+	}
+	for _, task := range report.Tasks {
+		if task.Id == "T1" && (task.Finish.Min < 2 || task.Finish.Max > 9) {
+			t.Errorf("Task T1 finish range [%d, %d] outside of estimates", task.Finish.Min, task.Finish.Max)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report, _ := proj.SimulateContext(ctx, 500); report != nil {
+		t.Errorf("A cancelled simulation should not report")
+	}
+	if proj.AddTaskEstimate("T3", 4, 2, 5, "pert") == "" {
+		t.Errorf("Inconsistent estimate accepted")
+	}
+}
+
+func TestRiskAnalysis(t *testing.T) {
+	proj := NewProject()
+	proj.AddTask("A", 10)
+	proj.AddTask("B", 4)
+	proj.AddTask("C", 2)
+	proj.AddTaskEstimate("A", 8, 10, 15, "triangular")
+	proj.AddTaskEstimate("B", 3, 4, 6, "triangular")
+	proj.AddTaskDependency("A", "C", common.FS)
+	proj.AddTaskDependency("B", "C", common.FS)
+	report, err := proj.AnalyzeRisk(300)
+	if err != "" {
+		t.Fatalf("Risk analysis failed - %s", err)
+	}
+	drivers := map[string]RiskDriver{}
+	for _, d := range report.Drivers {
+		drivers[d.Id] = d
+	}
+	if report.Drivers[0].Id != "A" {
+		t.Errorf("Got top risk driver '%s', expected 'A'", report.Drivers[0].Id)
+	}
+	if drivers["A"].CriticalityIndex != 1 || drivers["C"].CriticalityIndex != 1 {
+		t.Errorf("Tasks A and C must always be critical")
+	}
+	if drivers["B"].CriticalityIndex != 0 || drivers["B"].RiskScore != 0 {
+		t.Errorf("Task B must never be critical")
+	}
+	if drivers["A"].Sensitivity < 0.9 {
+		t.Errorf("Got sensitivity %.3f for task A, expected close to 1", drivers["A"].Sensitivity)
+	}
+}
+
+func TestRiskAnalysisSpareCapacity(t *testing.T) {
+	// A finishes when B starts on the same resource, but only binds B when the resource is full
+	for capacity, critical := range map[int]float64{10: 0, 1: 1} {
+		proj := NewProject()
+		proj.AddResource("R1", capacity)
+		proj.AddTask("X", 2)
+		proj.AddTask("A", 2)
+		proj.AddTask("B", 3)
+		proj.AddResourceAllocation("A", "R1", 1)
+		proj.AddResourceAllocation("B", "R1", 1)
+		proj.AddTaskDependency("X", "B", common.FS)
+		report, err := proj.AnalyzeRisk(10)
+		if err != "" {
+			t.Fatalf("Risk analysis failed - %s", err)
+		}
+		for _, d := range report.Drivers {
+			if d.Id == "A" && d.CriticalityIndex != critical {
+				t.Errorf("Got criticality index %.1f for A with capacity %d, expected %.1f", d.CriticalityIndex, capacity, critical)
+			}
+		}
+	}
+}
+
+func TestCrashPrecedenceNetwork(t *testing.T) {
+	proj := NewProject()
+	proj.AddTask("A", 5)
+	proj.AddTask("B", 6)
+	proj.AddTask("C", 4)
+	proj.AddTaskCrashOption("A", 3, 10)
+	proj.AddTaskCrashOption("B", 4, 5)
+	proj.AddTaskCrashOption("C", 2, 50)
+	proj.AddTaskDependency("A", "C", common.FS)
+	proj.AddTaskDependency("B", "C", common.FS)
+	proj.SetSolverParameters(0, 0, 0, 100)
+	report, err := proj.Crash(8)
+	if err != "" {
+		t.Fatalf("Crashing failed - %s", err)
+	}
+	if !report.Reached || report.Makespan != 8 {
+		t.Errorf("Got makespan %d, expected 8", report.Makespan)
+	}
+	if report.TotalCost != 20 {
+		t.Errorf("Got crash cost %d, expected 20", report.TotalCost)
+	}
+	if report.Schedule.CheckScheduleConsistency() != "" {
+		t.Errorf("Inconsistent crashed schedule")
+	}
+	report, err = proj.Crash(5)
+	if err != "" || report.Reached {
+		t.Errorf("Target below the crash limits must not be reached")
+	}
+}
+
+func TestCrashResourceConstrained(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	proj.AddTask("X", 4)
+	proj.AddTask("Y", 4)
+	proj.AddResourceAllocation("X", "R1", 1)
+	proj.AddResourceAllocation("Y", "R1", 1)
+	proj.AddTaskCrashOption("X", 2, 1)
+	proj.AddTaskCrashOption("Y", 1, 2)
+	proj.SetSolverParameters(0, 0, 0, 100)
+	report, err := proj.Crash(6)
+	if err != "" {
+		t.Fatalf("Crashing failed - %s", err)
+	}
+	if !report.Reached || report.TotalCost != 2 {
+		t.Errorf("Got makespan %d at cost %d, expected 6 at cost 2", report.Makespan, report.TotalCost)
+	}
+}
+
+func TestCrashReversal(t *testing.T) {
+	// X is the cheapest first compression, but P and Q alone reach 5 workdays, so X must be restored
+	proj := NewProject()
+	proj.AddTask("P", 2)
+	proj.AddTask("X", 3)
+	proj.AddTask("Q", 2)
+	proj.AddTask("S", 4)
+	proj.AddTask("R", 4)
+	proj.AddTaskCrashOption("P", 1, 3)
+	proj.AddTaskCrashOption("X", 1, 1)
+	proj.AddTaskCrashOption("Q", 1, 3)
+	proj.AddTaskDependency("P", "X", common.FS)
+	proj.AddTaskDependency("X", "Q", common.FS)
+	proj.AddTaskDependency("P", "S", common.FS)
+	proj.AddTaskDependency("R", "Q", common.FS)
+	proj.SetSolverParameters(0, 0, 0, 100)
+	report, err := proj.Crash(5)
+	if err != "" {
+		t.Fatalf("Crashing failed - %s", err)
+	}
+	if !report.Reached || report.TotalCost != 6 || len(report.Actions) != 2 || report.Actions[0].Id != "P" || report.Actions[1].Id != "Q" {
+		t.Errorf("Got %+v at cost %d, expected P and Q at cost 6", report.Actions, report.TotalCost)
+	}
+}
+
+func TestBaselineVariance(t *testing.T) {
+	xmlStr := `<project>
+    <calendar>
+        <kick-off-date>2024-07-01</kick-off-date>
+        <idle-week-days><idle-week-day>sunday</idle-week-day></idle-week-days>
+    </calendar>
+    <tasks>
+        <task id="T1">
+            <duration>3</duration>
+            <dependencies><dependency dependent-task-id="M1" type="FS"/></dependencies>
+        </task>
+        <task id="M1" milestone="true">
+            <duration>2</duration>
+        </task>
+    </tasks>
+    <baseline>
+        <task id="T1" start-t="0" finish-t="2"/>
+        <task id="M1" start-date="2024-07-03" finish-date="2024-07-04"/>
+    </baseline>
+</project>`
+	proj, err := ImportFromXmlString(xmlStr)
+	if err != "" {
+		t.Fatalf("Import failed - %s", err)
+	}
+	offset, _ := proj.calendar.dateToOffset("2024-07-08")
+	if offset != 6 {
+		t.Errorf("Got offset %d for 2024-07-08, expected 6", offset)
+	}
+	if !proj.Schedule(FIND_OPTIMAL) {
+		t.Fatalf("No schedule found")
+	}
+	comparison, err := proj.CompareToBaseline()
+	if err != "" {
+		t.Fatalf("Comparison failed - %s", err)
+	}
+	if comparison.MakespanChange != 1 {
+		t.Errorf("Got makespan change %d, expected 1", comparison.MakespanChange)
+	}
+	if len(comparison.SlippedMilestones) != 1 || comparison.SlippedMilestones[0].FinishVariance != 1 {
+		t.Errorf("Milestone M1 must have slipped by 1")
+	}
+	proj.SaveBaseline()
+	comparison, _ = proj.CompareToBaseline()
+	if comparison.MakespanChange != 0 || len(comparison.SlippedMilestones) != 0 {
+		t.Errorf("Schedule must not deviate from its own baseline")
+	}
+}
+
+func TestCriticalChainBuffers(t *testing.T) {
+	for _, sizing := range []string{"cut-and-paste", "root-square-error"} {
+		t.Run(sizing, func(t *testing.T) {
+			proj := NewProject()
+			proj.AddTask("A", 10)
+			proj.AddTask("B", 4)
+			proj.AddTask("C", 10)
+			proj.AddTaskDependency("A", "C", common.FS)
+			proj.AddTaskDependency("B", "C", common.FS)
+			proj.SetSolverParameters(0, 0, 0, 100)
+			report, err := proj.CriticalChain(sizing, 50)
+			if err != "" {
+				t.Fatalf("Critical chain failed - %s", err)
+			}
+			if len(report.Chain) != 2 || report.Chain[0] != "A" || report.Chain[1] != "C" {
+				t.Fatalf("Got chain %v, expected [A C]", report.Chain)
+			}
+			expectedPB, expectedFB := 5, 1
+			if sizing == "root-square-error" {
+				expectedPB, expectedFB = 8, 2
+			}
+			if len(report.Buffers) != 2 || report.Buffers[0].Size != expectedPB || report.Buffers[1].Size != expectedFB {
+				t.Fatalf("Got buffers %+v, expected sizes %d and %d", report.Buffers, expectedPB, expectedFB)
+			}
+			if report.Schedule.makespan != 10+expectedPB {
+				t.Errorf("Got makespan %d, expected %d", report.Schedule.makespan, 10+expectedPB)
+			}
+			if report.Buffers[0].StartT != 10 || report.Schedule.CheckScheduleConsistency() != "" {
+				t.Errorf("Project buffer must start right after the chain")
+			}
+		})
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	xmlStr := `<project>
+    <resources>
+        <resource id="TRUCK" capacity="2"/>
+    </resources>
+    <tasks>
+        <task id="T1">
+            <duration>3</duration>
+            <dependencies><dependency dependent-task-id="T3" type="FS"/></dependencies>
+            <allocations><allocation resource-id="TRUCK" level="1"/></allocations>
+        </task>
+        <task id="T2">
+            <duration>3</duration>
+            <allocations><allocation resource-id="TRUCK" level="1"/></allocations>
+        </task>
+        <task id="T3">
+            <duration>2</duration>
+        </task>
+    </tasks>
+    <scenarios>
+        <scenario name="lose a truck"><capacity resource-id="TRUCK" value="1"/></scenario>
+        <scenario name="T3 slips"><duration task-id="T3" value="6"/></scenario>
+        <scenario name="T3 released"><remove-dependency task-id="T1" dependent-task-id="T3"/></scenario>
+        <scenario name="no trucks"><capacity resource-id="TRUCK" value="0"/></scenario>
+    </scenarios>
+</project>`
+	proj, err := ImportFromXmlString(xmlStr)
+	if err != "" {
+		t.Fatalf("Import failed - %s", err)
+	}
+	proj.SetSolverParameters(0, 0, 0, 100)
+	report, err := proj.RunScenarios()
+	if err != "" {
+		t.Fatalf("Scenarios failed - %s", err)
+	}
+	if report.BaseMakespan != 5 {
+		t.Errorf("Got base makespan %d, expected 5", report.BaseMakespan)
+	}
+	expected := []int{6, 9, 3, common.UNDEF}
+	for i, sc := range report.Scenarios {
+		if sc.Makespan != expected[i] {
+			t.Errorf("Scenario '%s': got makespan %d, expected %d (%s)", sc.Name, sc.Makespan, expected[i], sc.Error)
+		}
+	}
+	if len(report.Scenarios[1].Diffs) == 0 || report.Scenarios[3].Error == "" {
+		t.Errorf("Missing scenario diffs or errors")
+	}
+	if _, exists := proj.tasks["T1"].taskDependencies["T3"]; !exists {
+		t.Errorf("Scenarios must not modify the base project")
+	}
+}
+
+func TestExplainSchedule(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 2)
+	proj.AddTask("D", 1)
+	proj.AddTaskDependency("A", "C", common.FS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 1)
+	proj.importSchedule(common.TaskSchedule{"A": 0, "B": 3, "C": 3, "D": 6})
+	proj.makespan = 7
+	explanations, err := proj.ExplainSchedule()
+	if err != "" {
+		t.Fatalf("Explanation failed - %s", err)
+	}
+	c := explanations["C"].Constraints
+	if len(c) != 1 || c[0].Kind != "dependency" || c[0].PredecessorId != "A" || c[0].DependencyType != "FS" {
+		t.Errorf("Got %+v for C, expected FS dependency on A", c)
+	}
+	b := explanations["B"].Constraints
+	if len(b) != 1 || b[0].Kind != "resource" || b[0].ResourceId != "R1" || b[0].T != 2 || len(b[0].HeldBy) != 1 || b[0].HeldBy[0] != "A" {
+		t.Errorf("Got %+v for B, expected R1 held by A at t=2", b)
+	}
+	if !explanations["D"].CanStartEarlier || explanations["A"].CanStartEarlier || len(explanations["A"].Constraints) != 0 {
+		t.Errorf("Only D should be able to start earlier")
+	}
+	if !strings.Contains(proj.ExportScheduleToStringXML(), "<resource id=\"R1\" t=\"2\"") {
+		t.Errorf("Schedule XML is missing the start constraints")
+	}
+	empty := NewProject()
+	if _, err := empty.ExplainSchedule(); err == "" {
+		t.Errorf("An empty project should not be explained")
+	}
+	if !strings.Contains(empty.ExportScheduleToStringXML(), "<schedule makespan=\"-1\">") {
+		t.Errorf("Empty project schedule XML is missing the undefined makespan")
+	}
+}
+
+func TestDiagnosis(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 4)
+	proj.AddTask("C", 2)
+	proj.AddTaskDependency("A", "B", common.FS)
+	proj.AddResourceAllocation("A", "R1", 2)
+	proj.AddResourceAllocation("C", "R1", 2)
+	d := proj.Diagnose(FIND_OPTIMAL)
+	if d.Structural || d.FeasibleMakespan != 7 {
+		t.Errorf("Got %+v, expected a search failure with a feasible makespan of 7", d)
+	}
+	d = proj.Diagnose(4)
+	if !d.Structural || len(d.Conflicts) != 2 || d.Conflicts[0].Type != MAKESPAN_TOO_SHORT || d.Conflicts[1].Type != RESOURCE_OVERLOAD {
+		t.Fatalf("Got %+v, expected makespan and resource conflicts", d.Conflicts)
+	}
+	if len(d.Conflicts[0].Dependencies) != 1 || d.Conflicts[0].Dependencies[0].DependentTaskId != "B" || len(d.Conflicts[1].Tasks) != 2 {
+		t.Errorf("Got %+v, expected the A -> B path and tasks A and C", d.Conflicts)
+	}
+	proj.AddTaskDependency("B", "C", common.SS)
+	proj.AddTaskDependency("C", "A", common.FF)
+	d = proj.Diagnose(FIND_OPTIMAL)
+	if !d.Structural || len(d.Conflicts) != 1 || d.Conflicts[0].Type != DEPENDENCY_CYCLE || len(d.Conflicts[0].Dependencies) != 3 {
+		t.Fatalf("Got %+v, expected a single dependency cycle", d.Conflicts)
+	}
+	if proj.Schedule(FIND_OPTIMAL) {
+		t.Errorf("Cyclic project must not be scheduled")
+	}
+	delete(proj.tasks["C"].taskDependencies, "A")
+	proj.resources["R1"] = resource{"R1", 1}
+	d = proj.Diagnose(FIND_OPTIMAL)
+	if len(d.Conflicts) != 2 || d.Conflicts[0].Type != OVERSIZED_DEMAND || d.Conflicts[0].Tasks[0] != "A" || d.Conflicts[1].Tasks[0] != "C" {
+		t.Errorf("Got %+v, expected the oversized demands of A and C", d.Conflicts)
+	}
+}
+
+func TestDependencyCycle(t *testing.T) {
+	xmlStr := `<project>
+    <tasks>
+        <task id="T1"><duration>1</duration><dependencies><dependency dependent-task-id="T3" type="FS"/></dependencies></task>
+        <task id="T3"><duration>2</duration><dependencies><dependency dependent-task-id="T7" type="FS"/></dependencies></task>
+        <task id="T7"><duration>2</duration><dependencies><dependency dependent-task-id="T9" type="SS"/></dependencies></task>
+        <task id="T9"><duration>1</duration><dependencies><dependency dependent-task-id="T3" type="FS"/></dependencies></task>
+    </tasks>
+</project>`
+	_, err := ImportFromXmlString(xmlStr)
+	if err != "Dependencies form a cycle: T3 -> T7 -> T9 -> T3" {
+		t.Errorf("Got error '%s', expected the T3 -> T7 -> T9 -> T3 cycle", err)
+	}
+	proj := NewProject()
+	proj.AddTask("A", 1)
+	proj.AddTask("B", 1)
+	proj.AddTask("C", 1)
+	proj.AddTaskDependency("A", "B", common.FS)
+	proj.AddTaskDependency("B", "C", common.FS)
+	proj.AddTaskDependency("C", "A", common.FS)
+	if proj.GetMinMakespan() != common.UNDEF || proj.Schedule(FIND_OPTIMAL) {
+		t.Errorf("Cyclic network must be rejected by the critical path computation")
+	}
+}
+
+func TestFloat(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 2)
+	proj.AddTask("D", 1)
+	proj.AddTaskDependency("A", "C", common.FS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 1)
+	proj.importSchedule(common.TaskSchedule{"A": 0, "B": 3, "C": 3, "D": 0})
+	proj.makespan = 5
+	report, err := proj.AnalyzeFloat()
+	if err != "" {
+		t.Fatalf("Float analysis failed - %s", err)
+	}
+	for _, id := range []string{"A", "B", "C"} {
+		if !report.Tasks[id].Critical || report.Tasks[id].FreeFloat != 0 {
+			t.Errorf("Got %+v, expected %s to be critical", report.Tasks[id], id)
+		}
+	}
+	if d := report.Tasks["D"]; d.Critical || d.TotalFloat != 4 || d.FreeFloat != 4 {
+		t.Errorf("Got %+v, expected D to float 4 workdays", d)
+	}
+	if fmt.Sprint(report.CriticalPaths) != "[[A B] [A C]]" {
+		t.Errorf("Got critical paths %v, expected [[A B] [A C]]", report.CriticalPaths)
+	}
+}
+
+func TestCriticalPathMixedDependencies(t *testing.T) {
+	proj := NewProject()
+	proj.AddTask("A", 4)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 3)
+	proj.AddTask("D", 1)
+	proj.AddTaskDependency("A", "B", common.FF)
+	proj.AddTaskDependency("B", "C", common.FS)
+	proj.AddTaskDependency("A", "D", common.SF)
+	if proj.GetMinMakespan() != 7 {
+		t.Fatalf("Got min makespan %d, expected 7", proj.GetMinMakespan())
+	}
+	expected := map[string][2]int{"A": {0, 0}, "B": {2, 2}, "C": {4, 4}, "D": {1, 6}}
+	for id, bounds := range expected {
+		if proj.tasks[id].earliestStart != bounds[0] || proj.tasks[id].latestStart != bounds[1] {
+			t.Errorf("Got ES=%d LS=%d for %s, expected %v", proj.tasks[id].earliestStart, proj.tasks[id].latestStart, id, bounds)
+		}
+	}
+}
+
+func TestResourceUsage(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddResource("R2", 1)
+	proj.AddResource("R3", 1)
+	proj.AddTask("A", 2)
+	proj.AddTask("B", 1)
+	proj.AddTask("C", 1)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 2)
+	proj.AddResourceAllocation("C", "R1", 1)
+	proj.AddResourceAllocation("C", "R3", 1)
+	// Capacity lowered below the allocation of C
+	proj.resources["R3"] = resource{"R3", 0}
+	proj.importSchedule(common.TaskSchedule{"A": 0, "B": 3, "C": 3})
+	proj.makespan = 4
+	report, err := proj.AnalyzeResourceUsage()
+	if err != "" {
+		t.Fatalf("Resource usage failed - %s", err)
+	}
+	r1, r2, r3 := report.Resources[0], report.Resources[1], report.Resources[2]
+	if fmt.Sprint(r1.Units) != "[1 1 0 3]" || r1.Peak != 3 || r1.Average != 1.25 || fmt.Sprint(r1.OverAllocated) != "[3]" {
+		t.Errorf("Got %+v for R1, expected units [1 1 0 3] over-allocated at t=3", r1)
+	}
+	if len(r1.IdlePeriods) != 1 || r1.IdlePeriods[0].StartT != 2 || r1.IdlePeriods[0].FinishT != 2 {
+		t.Errorf("Got idle periods %+v for R1, expected t=2 only", r1.IdlePeriods)
+	}
+	if r2.Utilization != 0 || len(r2.IdlePeriods) != 1 || r2.IdlePeriods[0].FinishT != 3 {
+		t.Errorf("Got %+v for R2, expected a single idle period", r2)
+	}
+	if r3.Utilization != 0 || fmt.Sprint(r3.OverAllocated) != "[3]" {
+		t.Errorf("Got %+v for R3, expected no utilization and over-allocation at t=3", r3)
+	}
+	csv := report.ExportToCSV()
+	if !strings.Contains(csv, "R1,3,") || !strings.HasSuffix(strings.Split(csv, "\n")[4], ",3,2,1.50,true") || !strings.HasSuffix(strings.Split(csv, "\n")[12], ",1,0,0.00,true") {
+		t.Errorf("Unexpected CSV export:\n%s", csv)
+	}
+}
+
+func TestRescheduleFromPrevious(t *testing.T) {
+	xmlStr := `<project>
+    <resources>
+        <resource id="R1" capacity="1"/>
+    </resources>
+    <tasks>
+        <task id="A"><duration>3</duration><allocations><allocation resource-id="R1" level="1"/></allocations></task>
+        <task id="B"><duration>2</duration><allocations><allocation resource-id="R1" level="1"/></allocations></task>
+        <task id="C"><duration>2</duration><allocations><allocation resource-id="R1" level="1"/></allocations></task>
+    </tasks>
+    <previous-schedule>
+        <task id="C" start-t="0"/>
+        <task id="A" start-t="2"/>
+        <task id="B" start-t="4"/>
+    </previous-schedule>
+</project>`
+	proj, err := ImportFromXmlString(xmlStr)
+	if err != "" {
+		t.Fatalf("Import failed - %s", err)
+	}
+	proj.SetSolverParameters(0, 0, 0, 100)
+	if !proj.Schedule(FIND_OPTIMAL) {
+		t.Fatalf("No schedule found")
+	}
+	if proj.tasks["C"].startT != 0 || proj.tasks["A"].startT != 2 || proj.tasks["B"].startT != 5 {
+		t.Errorf("Got %v, expected only B to slip after the longer A", proj.exportSchedule())
+	}
+	if !strings.Contains(proj.ExportScheduleToStringXML(), "<previous-start start-t=\"4\" shift=\"1\"/>") {
+		t.Errorf("Schedule XML is missing the shift of B")
+	}
+}
+
+func TestWarmStart(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	proj.AddTask("A", 2)
+	proj.AddTask("B", 3)
+	proj.AddTask("C", 1)
+	proj.AddTask("D", 2)
+	proj.AddTaskDependency("A", "D", common.FS)
+	for _, id := range []string{"A", "B", "C"} {
+		proj.AddResourceAllocation(id, "R1", 1)
+	}
+	initial := common.TaskSchedule{"A": 0, "B": 2, "C": 5, "D": 2}
+	for id, startT := range initial {
+		proj.SetInitialStart(id, startT)
+	}
+	// Without any search iteration, the feasible initial schedule is still the upper bound
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 6 {
+		t.Fatalf("Got makespan %d, expected the initial makespan 6", proj.makespan)
+	}
+	for id, startT := range initial {
+		if proj.tasks[id].startT != startT {
+			t.Errorf("Got start %d for %s, expected %d", proj.tasks[id].startT, id, startT)
+		}
+	}
+	if proj.SetInitialStart("X", 0) == "" {
+		t.Errorf("Initial start of an undefined task must be rejected")
+	}
+}
+
+type serialAlgorithm struct {
+	*solver.Solver
+}
+
+func (a serialAlgorithm) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	return a.SerialSchedule()
+}
+
+func TestAlgorithmRegistry(t *testing.T) {
+	solver.RegisterAlgorithm("test-serial", func(model common.ConstraintModel) solver.Algorithm {
+		return serialAlgorithm{solver.NewSolver(model)}
+	})
+	xmlStr := `<project>
+    <algorithm>test-serial</algorithm>
+    <resources><resource id="R1" capacity="1"/></resources>
+    <tasks>
+        <task id="A"><duration>2</duration><allocations><allocation resource-id="R1" level="1"/></allocations></task>
+        <task id="B"><duration>3</duration><allocations><allocation resource-id="R1" level="1"/></allocations></task>
+    </tasks>
+</project>`
+	proj, err := ImportFromXmlString(xmlStr)
+	if err != "" {
+		t.Fatalf("Import failed - %s", err)
+	}
+	// The default parameters run no search iteration, only the registered algorithm can succeed
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 5 {
+		t.Errorf("Got makespan %d, expected 5 from the serial algorithm", proj.makespan)
+	}
+	_, err = ImportFromXmlString(strings.Replace(xmlStr, "test-serial", "unknown", 1))
+	if err != "Unknown solver algorithm 'unknown'" {
+		t.Errorf("Got error '%s', expected an unknown algorithm", err)
+	}
+	if proj.SetAlgorithm(solver.DEFAULT_ALGORITHM) != "" {
+		t.Errorf("The local search must be registered by default")
+	}
+}
+
+func TestScheduleGenerationSchemes(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 2)
+	proj.AddTask("D", 1)
+	proj.AddTaskDependency("A", "D", common.FS)
+	proj.AddTaskDependency("B", "C", common.SS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 2)
+	proj.AddResourceAllocation("C", "R1", 1)
+	proj.criticalPath()
+	s := solver.NewSolver(*proj.buildConstraintModel())
+	for _, scheme := range []int{solver.SERIAL_SGS, solver.PARALLEL_SGS} {
+		for _, rule := range []int{solver.RULE_LST, solver.RULE_LFT, solver.RULE_MTS, solver.RULE_GRPW, solver.RULE_RANDOM} {
+			makespan, sched := s.ScheduleGeneration(scheme, s.Priorities(rule))
+			proj.importSchedule(sched)
+			proj.makespan = makespan
+			if sched == nil || proj.CheckScheduleConsistency() != "" {
+				t.Errorf("Inconsistent schedule %v for scheme %d and rule %d", sched, scheme, rule)
+			}
+		}
+	}
+	proj.SetAlgorithm(solver.CONSTRUCTIVE_ALGORITHM)
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 6 || proj.CheckScheduleConsistency() != "" {
+		t.Errorf("Got makespan %d, expected a consistent schedule of 6", proj.makespan)
+	}
+}
+
+func TestBranchAndBound(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddTask("A", 2)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 3)
+	proj.AddTaskDependency("A", "B", common.FS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 1)
+	proj.AddResourceAllocation("C", "R1", 2)
+	proj.SetAlgorithm(solver.BRANCH_AND_BOUND_ALGORITHM)
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 7 || !proj.IsProvenOptimal() || proj.GetLowerBound() != 7 {
+		t.Errorf("Got makespan %d and lower bound %d, expected a proven optimum of 7", proj.makespan, proj.GetLowerBound())
+	}
+	// A single node is not enough to close the gap left by the critical sequence bound
+	proj.SetSolverParameters(1, 0, 0, 0)
+	if !proj.Schedule(FIND_OPTIMAL) || proj.IsProvenOptimal() || proj.GetLowerBound() != 6 {
+		t.Errorf("Got lower bound %d, expected an unproven schedule bounded by 6", proj.GetLowerBound())
+	}
+	if !strings.Contains(proj.ExportScheduleToStringXML(), "lower-bound=\"6\" gap=\"1\" proven-optimal=\"false\"") {
+		t.Errorf("Schedule XML is missing the optimality gap")
+	}
+}
+
+func TestGeneticAlgorithm(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddTask("A", 2)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 3)
+	proj.AddTask("D", 4)
+	proj.AddTaskDependency("A", "B", common.FS)
+	proj.AddTaskDependency("C", "D", common.SS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 1)
+	proj.AddResourceAllocation("C", "R1", 2)
+	proj.AddResourceAllocation("D", "R1", 1)
+	proj.SetAlgorithm(solver.BRANCH_AND_BOUND_ALGORITHM)
+	if !proj.Schedule(FIND_OPTIMAL) || !proj.IsProvenOptimal() {
+		t.Fatalf("No proven optimum found for the reference instance")
+	}
+	optimum := proj.makespan
+	proj.SetAlgorithm(solver.GENETIC_ALGORITHM)
+	if !proj.Schedule(FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" {
+		t.Fatalf("Genetic algorithm produced no consistent schedule")
+	}
+	if proj.makespan != optimum {
+		t.Errorf("Got makespan %d, expected the optimum %d", proj.makespan, optimum)
+	}
+	if proj.Schedule(optimum - 1) {
+		t.Errorf("Schedule below the optimum should not be found")
+	}
+}
+
+func TestLargeNeighbourhoodSearch(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddResource("R2", 2)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 1)
+	proj.AddTask("C", 4)
+	proj.AddTask("D", 1)
+	proj.AddTask("E", 3)
+	proj.AddTask("F", 1)
+	proj.AddTaskDependency("A", "E", common.SS)
+	proj.AddTaskDependency("B", "C", common.SS)
+	proj.AddTaskDependency("C", "E", common.SS)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 2)
+	proj.AddResourceAllocation("B", "R2", 1)
+	proj.AddResourceAllocation("C", "R1", 2)
+	proj.AddResourceAllocation("C", "R2", 2)
+	proj.AddResourceAllocation("D", "R1", 1)
+	proj.AddResourceAllocation("D", "R2", 2)
+	proj.AddResourceAllocation("E", "R1", 1)
+	proj.AddResourceAllocation("E", "R2", 1)
+	proj.AddResourceAllocation("F", "R1", 1)
+	proj.AddResourceAllocation("F", "R2", 1)
+	// Under this seed the schedule generation starts one period above the optimum of 9, where R1 is never idle
+	proj.SetSeed(0)
+	proj.SetSolverParameters(0, 1, 0, 0)
+	proj.SetAlgorithm(solver.LNS_ALGORITHM)
+	makespans := []int{}
+	proj.SetProgress(func(progress solver.Progress) {
+		makespans = append(makespans, progress.Makespan)
+	})
+	if !proj.Schedule(FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" {
+		t.Fatalf("Large neighbourhood search produced no consistent schedule")
+	}
+	if len(makespans) < 2 || makespans[0] != 10 || proj.makespan != 9 || !proj.IsProvenOptimal() {
+		t.Errorf("Got makespans %v, expected the starting schedule of 10 improved to a proven 9", makespans)
+	}
+}
+
+func TestTabuSearchStatistics(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("T%d", i)
+		proj.AddTask(id, 2)
+		proj.AddResourceAllocation(id, "R1", 1)
+	}
+	proj.criticalPath()
+	s := solver.NewSolver(*proj.buildConstraintModel())
+	s.SetParameters(3000, 2, 0, 0)
+	// Overlapping starts, so the search cannot begin on a schedule
+	s.SetInitialAssignment(common.TaskSchedule{"T1": 0, "T2": 0, "T3": 0})
+	if s.SolveFixedMakespan(6) == nil || s.Stats().Assignments == 0 {
+		t.Errorf("Expected a schedule reached through assignments, got %s", s.ReportStats())
+	}
+	// No assignment fits, so the search keeps escaping local minima and restarting
+	s = solver.NewSolver(*proj.buildConstraintModel())
+	s.SetParameters(3000, 2, 0, 0)
+	if s.SolveFixedMakespan(5) != nil {
+		t.Fatalf("Three sequential tasks cannot fit in 5 periods")
+	}
+	stats := s.Stats()
+	if stats.Escapes == 0 || stats.Restarts == 0 || stats.BestScore == 0 {
+		t.Errorf("Expected escapes, restarts and a positive best score, got %s", s.ReportStats())
+	}
+}
+
+func TestPortfolio(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 3)
+	proj.AddTask("A", 2)
+	proj.AddTask("B", 2)
+	proj.AddTask("C", 2)
+	proj.AddResourceAllocation("A", "R1", 2)
+	proj.AddResourceAllocation("B", "R1", 2)
+	proj.AddResourceAllocation("C", "R1", 2)
+	if proj.SetPortfolio([]solver.PortfolioMember{{Algorithm: solver.PORTFOLIO_ALGORITHM}}, "") == "" || proj.SetPortfolio(nil, "fastest") == "" {
+		t.Errorf("Nested portfolios and unknown modes should be rejected")
+	}
+	// No two tasks fit side by side, so the optimum of 6 lies above the lower bound of 4 and only the exact member proves it
+	members := []solver.PortfolioMember{
+		{Algorithm: solver.DEFAULT_ALGORITHM},
+		{Algorithm: solver.BRANCH_AND_BOUND_ALGORITHM},
+	}
+	proj.SetAlgorithm(solver.PORTFOLIO_ALGORITHM)
+	proj.SetSolverParameters(0, 0, 0, 60000)
+	proj.SetPortfolio(members, solver.PORTFOLIO_FIRST)
+	start := time.Now()
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 6 || !proj.IsProvenOptimal() {
+		t.Errorf("Got makespan %d, expected the proven optimum of the exact member", proj.makespan)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("First portfolio result waited for the local search to run out of time")
+	}
+	proj.SetSolverParameters(100, 0, 0, 0)
+	proj.SetPortfolio(append(members, solver.PortfolioMember{Algorithm: solver.CONSTRUCTIVE_ALGORITHM}), solver.PORTFOLIO_BEST)
+	if !proj.Schedule(FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" || proj.makespan != 6 || !proj.IsProvenOptimal() {
+		t.Errorf("Got makespan %d, expected the best of all members proven optimal", proj.makespan)
+	}
+	// Members offer every schedule they find to the bound shared with the others
+	proj.criticalPath()
+	s := solver.NewSolver(*proj.buildConstraintModel())
+	s.SetParameters(100, 1, 0, 0)
+	bound := solver.NewSharedBound()
+	s.SetSharedBound(bound)
+	makespan, _ := s.SolveOptimalMakespan()
+	if makespan != 6 || bound.Makespan() != 6 {
+		t.Errorf("Got makespan %d and shared bound %d, expected both at 6", makespan, bound.Makespan())
+	}
+	// A finished portfolio stops the members still searching
+	s = solver.NewSolver(*proj.buildConstraintModel())
+	s.SetParameters(0, 1, 0, 60000)
+	bound.Finish()
+	s.SetSharedBound(bound)
+	start = time.Now()
+	if s.SolveFixedMakespan(4) != nil || time.Since(start) > 5*time.Second {
+		t.Errorf("Search below the optimum should stop once the portfolio has finished")
+	}
+}
+
+func TestScheduleContext(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 3)
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("T%d", i)
+		proj.AddTask(id, i)
+		proj.AddResourceAllocation(id, "R1", 2)
+	}
+	// No two tasks fit side by side, so below the optimum of 10 the searches would use up a minute per step
+	proj.SetSolverParameters(0, 4, 0, 60000)
+	for _, algorithm := range []string{solver.DEFAULT_ALGORITHM, solver.LNS_ALGORITHM, solver.GENETIC_ALGORITHM, solver.PORTFOLIO_ALGORITHM} {
+		proj.SetAlgorithm(algorithm)
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		if !proj.ScheduleContext(ctx, FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" || proj.makespan < 10 {
+			t.Errorf("Algorithm %s returned no schedule on the deadline", algorithm)
+		}
+		cancel()
+		ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+		if proj.ScheduleContext(ctx, 9) {
+			t.Errorf("Algorithm %s found an impossible schedule", algorithm)
+		}
+		cancel()
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("Algorithm %s ignored the context deadline", algorithm)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 2)
+	proj.AddTask("A", 3)
+	proj.AddTask("B", 3)
+	proj.AddTask("C", 4)
+	proj.AddTask("D", 3)
+	proj.AddTask("E", 4)
+	proj.AddTaskDependency("A", "C", common.FS)
+	proj.AddTaskDependency("B", "D", common.FF)
+	proj.AddResourceAllocation("A", "R1", 1)
+	proj.AddResourceAllocation("B", "R1", 1)
+	proj.AddResourceAllocation("C", "R1", 1)
+	proj.AddResourceAllocation("D", "R1", 1)
+	proj.AddResourceAllocation("E", "R1", 1)
+	proj.SetSolverParameters(500, 0, 0, 0)
+	for _, algorithm := range []string{solver.DEFAULT_ALGORITHM, solver.GENETIC_ALGORITHM, solver.BRANCH_AND_BOUND_ALGORITHM, solver.PORTFOLIO_ALGORITHM} {
+		reports := []solver.Progress{}
+		proj.SetProgress(func(progress solver.Progress) {
+			reports = append(reports, progress)
+		})
+		proj.SetAlgorithm(algorithm)
+		if !proj.Schedule(FIND_OPTIMAL) {
+			t.Fatalf("Algorithm %s found no schedule", algorithm)
+		}
+		if len(reports) == 0 || reports[len(reports)-1].Makespan != proj.makespan {
+			t.Errorf("Algorithm %s reported %d schedules, expected the last one with makespan %d", algorithm, len(reports), proj.makespan)
+		}
+		for i, progress := range reports {
+			if len(progress.Schedule) != 5 {
+				t.Errorf("Algorithm %s reported an incomplete schedule", algorithm)
+			}
+			if i > 0 && (progress.Makespan >= reports[i-1].Makespan || progress.Elapsed < reports[i-1].Elapsed) {
+				t.Errorf("Algorithm %s reported makespan %d after %d, expected improving schedules", algorithm, progress.Makespan, reports[i-1].Makespan)
+			}
+		}
+	}
+	// Under this seed the first schedule reported is one period above the optimum of 9, and stopping there keeps it
+	proj.SetSeed(0)
+	proj.SetAlgorithm(solver.DEFAULT_ALGORITHM)
+	ctx, cancel := context.WithCancel(context.Background())
+	proj.SetProgress(func(progress solver.Progress) {
+		cancel()
+	})
+	if !proj.ScheduleContext(ctx, FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" || proj.makespan != 10 {
+		t.Errorf("Got makespan %d, expected to stop with the first schedule reported", proj.makespan)
+	}
+}
+
+func TestLowerBounds(t *testing.T) {
+	proj := NewProject()
+	proj.AddResource("R1", 1)
+	for i := 1; i <= 3; i++ {
+		id := fmt.Sprintf("T%d", i)
+		proj.AddTask(id, 2)
+		proj.AddResourceAllocation(id, "R1", 1)
+	}
+	// The workload bound proves the schedule the local search finds
+	if !proj.Schedule(FIND_OPTIMAL) || proj.makespan != 6 || proj.GetLowerBound() != 6 || !proj.IsProvenOptimal() {
+		t.Errorf("Got makespan %d and lower bound %d, expected a proven optimum of 6", proj.makespan, proj.GetLowerBound())
+	}
+	if !strings.Contains(proj.ExportScheduleToStringXML(), "lower-bound=\"6\" gap=\"0\" proven-optimal=\"true\"") {
+		t.Errorf("Schedule XML is missing the lower bound")
+	}
+	// Never above the makespan of a schedule found by the exact search
+	random := rand.New(rand.NewSource(49))
+	for instance := 0; instance < 30; instance++ {
+		proj := NewProject()
+		proj.AddResource("R1", random.Intn(3)+2)
+		proj.AddResource("R2", random.Intn(3)+2)
+		for i := 1; i <= 8; i++ {
+			id := fmt.Sprintf("T%d", i)
+			proj.AddTask(id, random.Intn(5)+1)
+			proj.AddResourceAllocation(id, "R1", random.Intn(3))
+			proj.AddResourceAllocation(id, "R2", random.Intn(3))
+		}
+		for i := 1; i <= 6; i++ {
+			j := i + random.Intn(8-i) + 1
+			proj.AddTaskDependency(fmt.Sprintf("T%d", i), fmt.Sprintf("T%d", j), []int{common.FS, common.SS, common.FF}[random.Intn(3)])
+		}
+		proj.SetAlgorithm(solver.BRANCH_AND_BOUND_ALGORITHM)
+		if !proj.Schedule(FIND_OPTIMAL) || proj.CheckScheduleConsistency() != "" {
+			t.Fatalf("Instance %d: no consistent schedule found", instance)
+		}
+		s := solver.NewSolver(*proj.buildConstraintModel())
+		if s.LowerBound() < proj.minMakespan || s.LowerBound() > proj.makespan {
+			t.Errorf("Instance %d: lower bound %d outside [%d, %d]", instance, s.LowerBound(), proj.minMakespan, proj.makespan)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	build := func() *Project {
+		proj := NewProject()
+		proj.AddResource("R1", 2)
+		for i, id := range []string{"A", "B", "C", "D", "E", "F"} {
+			proj.AddTask(id, i%3+1)
+			proj.AddTaskEstimate(id, i%3+1, i%3+2, i%3+4, "pert")
+			proj.AddResourceAllocation(id, "R1", 1)
+		}
+		proj.AddTaskDependency("A", "D", common.FS)
+		proj.SetSeed(7)
+		proj.SetSolverParameters(3000, 4, 0, 0)
+		return proj
+	}
+	// Well above the optimum, where many schedules fit and the threads race for the best move
+	outputs := map[string]bool{}
+	for run := 0; run < 3; run++ {
+		proj := build()
+		for _, algorithm := range []string{solver.DEFAULT_ALGORITHM, solver.GENETIC_ALGORITHM, solver.LNS_ALGORITHM} {
+			proj.SetAlgorithm(algorithm)
+			if !proj.Schedule(12) {
+				t.Fatalf("Algorithm %s found no schedule within 12 workdays", algorithm)
+			}
+			outputs[algorithm+proj.ExportScheduleToStringXML()] = true
+		}
+		report, _ := proj.Simulate(50)
+		outputs[report.ExportToStringXML()] = true
+	}
+	if len(outputs) != 4 {
+		t.Errorf("Got %d distinct outputs over 3 runs, expected the same 4 every time", len(outputs))
+	}
+	// Portfolio members share makespans as they find them, which a seed cannot repeat
+	proj := build()
+	proj.SetAlgorithm(solver.PORTFOLIO_ALGORITHM)
+	if proj.SetPortfolio(nil, solver.PORTFOLIO_FIRST) == "" || proj.Schedule(FIND_OPTIMAL) {
+		t.Errorf("A seeded portfolio should be rejected")
+	}
+	var w strings.Builder
+	build().ExportToXML(&w)
+	imported, err := ImportFromXmlString(w.String())
+	if err != "" || !imported.parameters.seeded || imported.parameters.seed != 7 {
+		t.Errorf("Seed lost on export and import - %s", err)
+	}
+}
+
+func TestScheduleLadder(t *testing.T) {
+	// The path of the schedule service, whose ladder of times would give a minute to every step of the search
+	start := time.Now()
+	outputs := map[string]bool{}
+	for run := 0; run < 3; run++ {
+		proj := NewProject()
+		proj.AddResource("R1", 3)
+		for i := 1; i <= 5; i++ {
+			id := fmt.Sprintf("T%d", i)
+			proj.AddTask(id, i)
+			proj.AddResourceAllocation(id, "R1", 2)
+		}
+		proj.SetSeed(5)
+		proj.SetSolverParameters(0, 4, 0, 0)
+		if !proj.ScheduleLadder(context.Background(), []int{60000}, []int{200}) || proj.CheckScheduleConsistency() != "" {
+			t.Fatalf("Seeded ladder produced no consistent schedule")
+		}
+		outputs[proj.ExportScheduleToStringXML()] = true
+	}
+	if len(outputs) != 1 {
+		t.Errorf("Got %d distinct schedules over 3 runs, expected the seed to repeat the same one", len(outputs))
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Seeded ladder was limited by time instead of iterations")
+	}
+}
+
 func TestIterateAll(t *testing.T) {
 	if !testIterateAll {
 		return
@@ -0,0 +1,114 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type RiskDriver struct {
+	Id               string
+	CriticalityIndex float64
+	Sensitivity      float64
+	RiskScore        float64
+}
+
+type RiskReport struct {
+	Runs    int
+	Drivers []RiskDriver
+}
+
+func correlation(x []int, y []int) float64 {
+	n := float64(len(x))
+	var sumX, sumY float64
+	for i := range x {
+		sumX += float64(x[i])
+		sumY += float64(y[i])
+	}
+	meanX, meanY := sumX/n, sumY/n
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := float64(x[i])-meanX, float64(y[i])-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0 // Fixed durations or makespan do not correlate with anything
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+func (p *Project) AnalyzeRisk(runs int) (*RiskReport, string) {
+	return p.AnalyzeRiskContext(context.Background(), runs)
+}
+
+func (p *Project) AnalyzeRiskContext(ctx context.Context, runs int) (*RiskReport, string) {
+	// A cancelled context stops the runs without a report
+	runs, err := p.prepareSimulation(runs)
+	if err != "" {
+		return nil, err
+	}
+	makespans := []int{}
+	durationSamples := map[string][]int{}
+	criticalRuns := map[string]int{}
+	err = p.runSimulation(ctx, runs, func(durations map[string]int, s *solver.Solver, makespan int, sched common.TaskSchedule) {
+		makespans = append(makespans, makespan)
+		for id, d := range durations {
+			durationSamples[id] = append(durationSamples[id], d)
+		}
+		for _, id := range s.CriticalTasks() {
+			criticalRuns[id]++
+		}
+	})
+	if err != "" {
+		return nil, err
+	}
+	report := RiskReport{runs, []RiskDriver{}}
+	for _, id := range p.sortedTaskIds() {
+		ci := float64(criticalRuns[id]) / float64(runs)
+		sensitivity := correlation(durationSamples[id], makespans)
+		report.Drivers = append(report.Drivers, RiskDriver{id, ci, sensitivity, ci * sensitivity})
+	}
+	sort.SliceStable(report.Drivers, func(i, j int) bool {
+		a, b := report.Drivers[i], report.Drivers[j]
+		if a.RiskScore != b.RiskScore {
+			return a.RiskScore > b.RiskScore
+		}
+		return a.CriticalityIndex > b.CriticalityIndex
+	})
+	return &report, ""
+}
+
+func (r *RiskReport) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<risk-analysis runs=\"%d\">\n", r.Runs)
+	for i, d := range r.Drivers {
+		fmt.Fprintf(&w, "%s<task id=\"%s\" rank=\"%d\" criticality-index=\"%.3f\" sensitivity=\"%.3f\" risk-score=\"%.3f\"/>\n", xmlIndent, d.Id, i+1, d.CriticalityIndex, d.Sensitivity, d.RiskScore)
+	}
+	fmt.Fprintf(&w, "</risk-analysis>\n")
+	return w.String()
+}
@@ -0,0 +1,210 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	durationChange = iota
+	capacityChange
+	dependencyAddition
+	dependencyRemoval
+)
+
+type scenarioChange struct {
+	kind  int
+	id1   string
+	id2   string
+	value int
+}
+
+type Scenario struct {
+	name    string
+	changes []scenarioChange
+}
+
+type TaskDiff struct {
+	Id          string
+	BaseStartT  int
+	StartT      int
+	StartDate   string
+	BaseFinishT int
+	FinishT     int
+	FinishDate  string
+}
+
+type ScenarioResult struct {
+	Name           string
+	Error          string
+	Makespan       int
+	MakespanChange int
+	Diffs          []TaskDiff
+	Schedule       *Project
+}
+
+type ScenarioReport struct {
+	BaseMakespan int
+	Base         *Project
+	Scenarios    []ScenarioResult
+}
+
+func NewScenario(name string) *Scenario {
+	return &Scenario{name, []scenarioChange{}}
+}
+
+func (sc *Scenario) SetDuration(taskId string, duration int) {
+	sc.changes = append(sc.changes, scenarioChange{durationChange, taskId, "", duration})
+}
+
+func (sc *Scenario) SetCapacity(resourceId string, capacity int) {
+	sc.changes = append(sc.changes, scenarioChange{capacityChange, resourceId, "", capacity})
+}
+
+func (sc *Scenario) AddDependency(firstTaskId string, secondTaskId string, dependencyType int) {
+	sc.changes = append(sc.changes, scenarioChange{dependencyAddition, firstTaskId, secondTaskId, dependencyType})
+}
+
+func (sc *Scenario) RemoveDependency(firstTaskId string, secondTaskId string) {
+	sc.changes = append(sc.changes, scenarioChange{dependencyRemoval, firstTaskId, secondTaskId, common.UNDEF})
+}
+
+func (p *Project) AddScenario(sc *Scenario) string {
+	if sc.name == "" {
+		return "Scenario is missing a name"
+	}
+	for _, other := range p.scenarios {
+		if other.name == sc.name {
+			return fmt.Sprintf("Duplicate scenario '%s'", sc.name)
+		}
+	}
+	for _, change := range sc.changes {
+		if change.kind == capacityChange {
+			_, exists := p.resources[change.id1]
+			if !exists {
+				return fmt.Sprintf("Undefined resource '%s' in scenario '%s'", change.id1, sc.name)
+			}
+			continue
+		}
+		taskIds := []string{change.id1}
+		if change.kind != durationChange {
+			taskIds = append(taskIds, change.id2)
+		}
+		for _, id := range taskIds {
+			_, exists := p.tasks[id]
+			if !exists {
+				return fmt.Sprintf("Undefined task '%s' in scenario '%s'", id, sc.name)
+			}
+		}
+	}
+	p.scenarios = append(p.scenarios, sc)
+	return ""
+}
+
+func (p *Project) applyScenario(sc *Scenario) string {
+	for _, change := range sc.changes {
+		switch change.kind {
+		case durationChange:
+			if change.value <= 0 {
+				return fmt.Sprintf("Task '%s' has zero or negative duration", change.id1)
+			}
+			p.setTaskDuration(change.id1, change.value)
+		case capacityChange:
+			for _, t := range p.tasks {
+				if t.resourceAllocations[change.id1] > change.value {
+					return fmt.Sprintf("Resource '%s' allocation for task '%s' exceeds resource capacity", change.id1, t.id)
+				}
+			}
+			p.resources[change.id1] = resource{change.id1, change.value}
+		case dependencyAddition:
+			err := p.AddTaskDependency(change.id1, change.id2, change.value)
+			if err != "" {
+				return err
+			}
+		case dependencyRemoval:
+			_, exists := p.tasks[change.id1].taskDependencies[change.id2]
+			if !exists {
+				return fmt.Sprintf("No dependency defined between '%s' and '%s'", change.id1, change.id2)
+			}
+			delete(p.tasks[change.id1].taskDependencies, change.id2)
+		}
+	}
+	return ""
+}
+
+func (p *Project) RunScenarios() (*ScenarioReport, string) {
+	base := p.clone()
+	if !base.Schedule(FIND_OPTIMAL) {
+		return nil, "No schedule found for the base project"
+	}
+	report := ScenarioReport{base.makespan, base, make([]ScenarioResult, len(p.scenarios))}
+	var wg sync.WaitGroup
+	for i, sc := range p.scenarios {
+		wg.Add(1)
+		go func(i int, sc *Scenario) {
+			defer wg.Done()
+			result := ScenarioResult{sc.name, "", common.UNDEF, 0, []TaskDiff{}, nil}
+			c := p.clone()
+			result.Error = c.applyScenario(sc)
+			if result.Error == "" {
+				result.Error = c.checkDependencyCycles()
+			}
+			if result.Error == "" && !c.Schedule(FIND_OPTIMAL) {
+				result.Error = "No schedule found"
+			}
+			if result.Error == "" {
+				result.Makespan = c.makespan
+				result.MakespanChange = c.makespan - base.makespan
+				result.Schedule = c
+				for _, id := range c.sortedTaskIds() {
+					b, t := base.tasks[id], c.tasks[id]
+					if b.startT != t.startT || b.finishT != t.finishT {
+						result.Diffs = append(result.Diffs, TaskDiff{id, b.startT, t.startT, t.startDate, b.finishT, t.finishT, t.finishDate})
+					}
+				}
+			}
+			report.Scenarios[i] = result
+		}(i, sc)
+	}
+	wg.Wait()
+	return &report, ""
+}
+
+func (r *ScenarioReport) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<scenarios base-makespan=\"%d\">\n", r.BaseMakespan)
+	for _, sc := range r.Scenarios {
+		if sc.Error != "" {
+			fmt.Fprintf(&w, "%s<scenario name=\"%s\" error=\"%s\"/>\n", xmlIndent, sc.Name, sc.Error)
+			continue
+		}
+		fmt.Fprintf(&w, "%s<scenario name=\"%s\" makespan=\"%d\" makespan-change=\"%d\">\n", xmlIndent, sc.Name, sc.Makespan, sc.MakespanChange)
+		for _, d := range sc.Diffs {
+			fmt.Fprintf(&w, "%s<task id=\"%s\" start-t=\"%d\" start-date=\"%s\" start-shift=\"%d\" finish-t=\"%d\" finish-date=\"%s\" finish-shift=\"%d\"/>\n", strings.Repeat(xmlIndent, 2), d.Id, d.StartT, d.StartDate, d.StartT-d.BaseStartT, d.FinishT, d.FinishDate, d.FinishT-d.BaseFinishT)
+		}
+		fmt.Fprintf(&w, "%s</scenario>\n", xmlIndent)
+	}
+	fmt.Fprintf(&w, "</scenarios>\n")
+	return w.String()
+}
@@ -0,0 +1,292 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TRIANGULAR = iota
+	PERT
+	UNIFORM
+)
+
+const DEFAULT_SIMULATION_RUNS = 1000
+
+var simulationPercentiles = []int{50, 80, 95}
+
+type durationEstimate struct {
+	optimistic   int
+	mostLikely   int
+	pessimistic  int
+	distribution int
+}
+
+type Percentile struct {
+	Level int
+	T     int
+	Date  string
+}
+
+type DistributionSummary struct {
+	Min         int
+	Max         int
+	Mean        float64
+	StdDev      float64
+	Percentiles []Percentile
+}
+
+type TaskSimulationSummary struct {
+	Id     string
+	Finish DistributionSummary
+}
+
+type SimulationReport struct {
+	Runs       int
+	Completion DistributionSummary
+	Tasks      []TaskSimulationSummary
+}
+
+func distTextToType(distText string) int {
+	switch strings.ToLower(distText) {
+	case "", "triangular":
+		return TRIANGULAR
+	case "pert":
+		return PERT
+	case "uniform":
+		return UNIFORM
+	}
+	return common.UNDEF
+}
+
+func distTypeToText(distType int) string {
+	switch distType {
+	case TRIANGULAR:
+		return "triangular"
+	case PERT:
+		return "pert"
+	case UNIFORM:
+		return "uniform"
+	}
+	return ""
+}
+
+func sampleGamma(random *rand.Rand, alpha float64) float64 {
+	// Marsaglia and Tsang method, valid for alpha >= 1
+	d := alpha - 1.0/3.0
+	c := 1.0 / math.Sqrt(9*d)
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := random.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+func (e *durationEstimate) sample(random *rand.Rand) int {
+	a := float64(e.optimistic)
+	m := float64(e.mostLikely)
+	b := float64(e.pessimistic)
+	if b == a {
+		return e.mostLikely
+	}
+	var x float64
+	switch e.distribution {
+	case TRIANGULAR:
+		u := random.Float64()
+		if u < (m-a)/(b-a) {
+			x = a + math.Sqrt(u*(b-a)*(m-a))
+		} else {
+			x = b - math.Sqrt((1-u)*(b-a)*(b-m))
+		}
+	case PERT:
+		g1 := sampleGamma(random, 1+4*(m-a)/(b-a))
+		g2 := sampleGamma(random, 1+4*(b-m)/(b-a))
+		x = a + (b-a)*g1/(g1+g2)
+	case UNIFORM:
+		x = a + (b-a)*random.Float64()
+	}
+	d := int(math.Round(x))
+	if d < 1 {
+		d = 1
+	}
+	return d
+}
+
+func (p *Project) sampleDurations(model *common.ConstraintModel, random *rand.Rand) map[string]int {
+	// Tasks are sampled in the order of their ids, so that a seed gives the same durations
+	durations := map[string]int{}
+	for _, id := range p.sortedTaskIds() {
+		t := p.tasks[id]
+		d := t.duration
+		if t.estimate != nil {
+			d = t.estimate.sample(random)
+		}
+		durations[id] = d
+		model.AddTaskDefinition(id, d, t.earliestStart, t.latestStart)
+	}
+	return durations
+}
+
+func (p *Project) prepareSimulation(runs int) (int, string) {
+	if runs <= 0 {
+		runs = DEFAULT_SIMULATION_RUNS
+	}
+	if len(p.tasks) == 0 {
+		return runs, "Project has no tasks"
+	}
+	p.criticalPath()
+	for _, t := range p.tasks {
+		if t.earliestStart < 0 || t.earliestFinish < 0 {
+			return runs, "Inconsistent precedence constraints"
+		}
+	}
+	return runs, ""
+}
+
+func percentileOf(sorted []int, level int) int {
+	rank := int(math.Ceil(float64(level)/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func summarizeSamples(samples []int, dateOf func(int) string) DistributionSummary {
+	sorted := append([]int{}, samples...)
+	sort.Ints(sorted)
+	sum := 0.0
+	for _, x := range sorted {
+		sum += float64(x)
+	}
+	mean := sum / float64(len(sorted))
+	variance := 0.0
+	for _, x := range sorted {
+		variance += (float64(x) - mean) * (float64(x) - mean)
+	}
+	summary := DistributionSummary{sorted[0], sorted[len(sorted)-1], mean, math.Sqrt(variance / float64(len(sorted))), []Percentile{}}
+	for _, level := range simulationPercentiles {
+		t := percentileOf(sorted, level)
+		summary.Percentiles = append(summary.Percentiles, Percentile{level, t, dateOf(t)})
+	}
+	return summary
+}
+
+func (p *Project) runSimulation(ctx context.Context, runs int, observe func(durations map[string]int, s *solver.Solver, makespan int, sched common.TaskSchedule)) string {
+	model := p.buildConstraintModel()
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if p.parameters.seeded {
+		random = rand.New(rand.NewSource(p.parameters.seed))
+	}
+	for run := 0; run < runs; run++ {
+		if ctx.Err() != nil {
+			return "Simulation cancelled"
+		}
+		durations := p.sampleDurations(model, random)
+		s := solver.NewSolver(*model)
+		makespan, sched := s.SerialSchedule()
+		if sched == nil {
+			return "No schedule could be generated for the sampled durations"
+		}
+		observe(durations, s, makespan, sched)
+	}
+	return ""
+}
+
+func (p *Project) sortedTaskIds() []string {
+	taskIds := []string{}
+	for id := range p.tasks {
+		taskIds = append(taskIds, id)
+	}
+	sort.Strings(taskIds)
+	return taskIds
+}
+
+func (p *Project) Simulate(runs int) (*SimulationReport, string) {
+	return p.SimulateContext(context.Background(), runs)
+}
+
+func (p *Project) SimulateContext(ctx context.Context, runs int) (*SimulationReport, string) {
+	// A cancelled context stops the runs without a report
+	runs, err := p.prepareSimulation(runs)
+	if err != "" {
+		return nil, err
+	}
+	makespans := []int{}
+	finishes := map[string][]int{}
+	maxMakespan := 0
+	err = p.runSimulation(ctx, runs, func(durations map[string]int, s *solver.Solver, makespan int, sched common.TaskSchedule) {
+		makespans = append(makespans, makespan)
+		if makespan > maxMakespan {
+			maxMakespan = makespan
+		}
+		for id, startT := range sched {
+			finishes[id] = append(finishes[id], startT+durations[id]-1)
+		}
+	})
+	if err != "" {
+		return nil, err
+	}
+	p.calendar.buildDateMap(maxMakespan)
+	report := SimulationReport{runs, DistributionSummary{}, []TaskSimulationSummary{}}
+	report.Completion = summarizeSamples(makespans, func(t int) string { return p.calendar.dateMap[t-1] })
+	for _, id := range p.sortedTaskIds() {
+		summary := summarizeSamples(finishes[id], func(t int) string { return p.calendar.dateMap[t] })
+		report.Tasks = append(report.Tasks, TaskSimulationSummary{id, summary})
+	}
+	return &report, ""
+}
+
+func writeDistributionSummary(w *strings.Builder, tag string, attrs string, tAttr string, dateAttr string, summary DistributionSummary, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	fmt.Fprintf(w, "%s<%s%s min=\"%d\" max=\"%d\" mean=\"%.2f\" std-dev=\"%.2f\">\n", indent, tag, attrs, summary.Min, summary.Max, summary.Mean, summary.StdDev)
+	for _, pct := range summary.Percentiles {
+		fmt.Fprintf(w, "%s<percentile level=\"%d\" %s=\"%d\" %s=\"%s\"/>\n", indent+xmlIndent, pct.Level, tAttr, pct.T, dateAttr, pct.Date)
+	}
+	fmt.Fprintf(w, "%s</%s>\n", indent, tag)
+}
+
+func (r *SimulationReport) ExportToStringXML() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&w, "<simulation runs=\"%d\">\n", r.Runs)
+	writeDistributionSummary(&w, "completion", "", "makespan", "date", r.Completion, 1)
+	fmt.Fprintf(&w, "%s<tasks>\n", xmlIndent)
+	for _, t := range r.Tasks {
+		writeDistributionSummary(&w, "task", fmt.Sprintf(" id=\"%s\"", t.Id), "finish-t", "finish-date", t.Finish, 2)
+	}
+	fmt.Fprintf(&w, "%s</tasks>\n", xmlIndent)
+	fmt.Fprintf(&w, "</simulation>\n")
+	return w.String()
+}
@@ -0,0 +1,137 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package project
+
+import (
+	"goproj/common"
+	"goproj/solver"
+	"fmt"
+	"strings"
+)
+
+type IdlePeriod struct {
+	StartT     int
+	StartDate  string
+	FinishT    int
+	FinishDate string
+}
+
+type ResourceUsage struct {
+	Id            string
+	Capacity      int
+	Units         []int
+	Dates         []string
+	Peak          int
+	Average       float64
+	Utilization   float64
+	OverAllocated []int
+	IdlePeriods   []IdlePeriod
+}
+
+type UsageReport struct {
+	Makespan  int
+	Resources []ResourceUsage
+}
+
+func (p *Project) AnalyzeResourceUsage() (*UsageReport, string) {
+	if p.makespan <= 0 {
+		return nil, "Project has not been scheduled"
+	}
+	for _, t := range p.tasks {
+		if t.startT == common.UNDEF {
+			return nil, "Project has not been scheduled"
+		}
+	}
+	model := p.buildConstraintModel()
+	s := solver.NewSolver(*model)
+	profile := s.ResourceProfile(p.makespan, p.exportSchedule())
+	p.calendar.buildDateMap(p.makespan)
+	dates := make([]string, p.makespan)
+	for t := range dates {
+		dates[t] = p.calendar.dateMap[t]
+	}
+	report := UsageReport{p.makespan, []ResourceUsage{}}
+	for _, id := range p.sortedResourceIds() {
+		capacity := p.resources[id].capacity
+		usage := ResourceUsage{id, capacity, profile[id], dates, 0, 0, 0, []int{}, []IdlePeriod{}}
+		total := 0
+		idleStart := common.UNDEF
+		for t, units := range usage.Units {
+			total += units
+			if units > usage.Peak {
+				usage.Peak = units
+			}
+			if units > capacity {
+				usage.OverAllocated = append(usage.OverAllocated, t)
+			}
+			if units == 0 && idleStart == common.UNDEF {
+				idleStart = t
+			}
+			if units > 0 && idleStart != common.UNDEF {
+				usage.IdlePeriods = append(usage.IdlePeriods, IdlePeriod{idleStart, dates[idleStart], t - 1, dates[t-1]})
+				idleStart = common.UNDEF
+			}
+		}
+		if idleStart != common.UNDEF {
+			usage.IdlePeriods = append(usage.IdlePeriods, IdlePeriod{idleStart, dates[idleStart], p.makespan - 1, dates[p.makespan-1]})
+		}
+		usage.Average = float64(total) / float64(p.makespan)
+		// Any use of a resource without capacity is flagged as over-allocation instead
+		if capacity > 0 {
+			usage.Utilization = usage.Average / float64(capacity)
+		}
+		report.Resources = append(report.Resources, usage)
+	}
+	return &report, ""
+}
+
+func (r *UsageReport) writeXML(w *strings.Builder, level int) {
+	indent := strings.Repeat(xmlIndent, level)
+	fmt.Fprintf(w, "%s<resource-usage>\n", indent)
+	for _, u := range r.Resources {
+		fmt.Fprintf(w, "%s<resource id=\"%s\" capacity=\"%d\" peak=\"%d\" average=\"%.2f\" utilization=\"%.2f\" over-allocated=\"%t\">\n", strings.Repeat(xmlIndent, level+1), u.Id, u.Capacity, u.Peak, u.Average, u.Utilization, len(u.OverAllocated) > 0)
+		for t, units := range u.Units {
+			fmt.Fprintf(w, "%s<usage t=\"%d\" date=\"%s\" units=\"%d\"", strings.Repeat(xmlIndent, level+2), t, u.Dates[t], units)
+			if units > u.Capacity {
+				fmt.Fprintf(w, " over-allocated=\"true\"")
+			}
+			fmt.Fprintf(w, "/>\n")
+		}
+		for _, idle := range u.IdlePeriods {
+			fmt.Fprintf(w, "%s<idle start-t=\"%d\" start-date=\"%s\" finish-t=\"%d\" finish-date=\"%s\"/>\n", strings.Repeat(xmlIndent, level+2), idle.StartT, idle.StartDate, idle.FinishT, idle.FinishDate)
+		}
+		fmt.Fprintf(w, "%s</resource>\n", strings.Repeat(xmlIndent, level+1))
+	}
+	fmt.Fprintf(w, "%s</resource-usage>\n", indent)
+}
+
+func (r *UsageReport) ExportToCSV() string {
+	var w strings.Builder
+	fmt.Fprintf(&w, "resource,t,date,units,capacity,utilization,over-allocated\n")
+	for _, u := range r.Resources {
+		for t, units := range u.Units {
+			utilization := 0.0
+			if u.Capacity > 0 {
+				utilization = float64(units) / float64(u.Capacity)
+			}
+			fmt.Fprintf(&w, "%s,%d,%s,%d,%d,%.2f,%t\n", u.Id, t, u.Dates[t], units, u.Capacity, utilization, units > u.Capacity)
+		}
+	}
+	return w.String()
+}
@@ -0,0 +1,133 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const DEFAULT_ALGORITHM = "local-search"
+
+type Algorithm interface {
+	SetParameters(maxIterations int, threads int, step int, maxTime int)
+	SetReference(schedule common.TaskSchedule)
+	SetInitialAssignment(schedule common.TaskSchedule)
+	SolveFixedMakespan(makespan int) common.TaskSchedule
+	SolveOptimalMakespan() (int, common.TaskSchedule)
+	Stats() Statistics
+}
+
+type ContextAlgorithm interface {
+	SetContext(ctx context.Context)
+}
+
+type SeededAlgorithm interface {
+	SetSeed(seed int64)
+}
+
+type BoundedAlgorithm interface {
+	LowerBound() int
+	Proven() bool
+}
+
+type AlgorithmFactory func(model common.ConstraintModel) Algorithm
+
+var (
+	algorithms    = map[string]AlgorithmFactory{}
+	mutexRegistry sync.Mutex
+)
+
+func init() {
+	RegisterAlgorithm(DEFAULT_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewSolver(model)
+	})
+	RegisterAlgorithm(CONSTRUCTIVE_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewConstructiveSolver(model)
+	})
+	RegisterAlgorithm(BRANCH_AND_BOUND_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewBranchAndBoundSolver(model)
+	})
+	RegisterAlgorithm(GENETIC_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewGeneticSolver(model)
+	})
+	RegisterAlgorithm(LNS_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewLNSSolver(model)
+	})
+	RegisterAlgorithm(PORTFOLIO_ALGORITHM, func(model common.ConstraintModel) Algorithm {
+		return NewPortfolioSolver(model)
+	})
+}
+
+func RegisterAlgorithm(name string, factory AlgorithmFactory) {
+	mutexRegistry.Lock()
+	algorithms[name] = factory
+	mutexRegistry.Unlock()
+}
+
+func HasAlgorithm(name string) bool {
+	mutexRegistry.Lock()
+	_, exists := algorithms[name]
+	mutexRegistry.Unlock()
+	return exists
+}
+
+func Algorithms() []string {
+	mutexRegistry.Lock()
+	names := []string{}
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	mutexRegistry.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+func NewAlgorithm(name string, model common.ConstraintModel) (Algorithm, string) {
+	if name == "" {
+		name = DEFAULT_ALGORITHM
+	}
+	mutexRegistry.Lock()
+	factory, exists := algorithms[name]
+	mutexRegistry.Unlock()
+	if !exists {
+		return nil, fmt.Sprintf("Unknown solver algorithm '%s'", name)
+	}
+	return factory(model), ""
+}
+
+func SolveOptimalMakespanContext(ctx context.Context, a Algorithm) (int, common.TaskSchedule) {
+	// Once the context is done, the algorithm stops promptly with the best schedule found so far
+	contextual, isContextual := a.(ContextAlgorithm)
+	if isContextual {
+		contextual.SetContext(ctx)
+	}
+	return a.SolveOptimalMakespan()
+}
+
+func SolveFixedMakespanContext(ctx context.Context, a Algorithm, makespan int) common.TaskSchedule {
+	contextual, isContextual := a.(ContextAlgorithm)
+	if isContextual {
+		contextual.SetContext(ctx)
+	}
+	return a.SolveFixedMakespan(makespan)
+}
@@ -0,0 +1,201 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"sort"
+)
+
+func (s *Solver) ImportSolution(schedule common.TaskSchedule) {
+	for taskId, startT := range schedule {
+		varId, exists := s.varTranslations[taskId]
+		if exists {
+			s.variables[varId].value = startT
+		}
+	}
+}
+
+func (s *Solver) sharesResource(varA int, varB int) bool {
+	for r := range s.capacities {
+		if s.allocations.GetCell(varA, r) > 0 && s.allocations.GetCell(varB, r) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Solver) lacksRoomFor(u int, v int, t int) bool {
+	// Whether a resource held by u at t would exceed its capacity if v started at t
+	for r := range s.capacities {
+		if s.allocations.GetCell(u, r) > 0 && s.allocations.GetCell(v, r) > 0 && s.evalResources(s.resourcesOffset+r*s.makespan+t, v, t) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Solver) stockWorkspace(makespan int) {
+	// Resource stocks left by the current starts, against which binding resources are evaluated
+	s.buildWorkspace(makespan)
+	for v := range s.variables {
+		if s.variables[v].value > common.UNDEF {
+			s.updateStock(v, s.variables[v].value, STOCK_DOWN)
+		}
+	}
+}
+
+func (s *Solver) bindingPredecessors(v int) []int {
+	// Tasks that prevent v from starting any earlier: dependency predecessors whose minimum delay
+	// is exactly met, and tasks finishing right before it starts on a resource left without room
+	// for v one period earlier
+	preds := []int{}
+	startT := s.variables[v].value
+	if startT <= 0 {
+		return preds
+	}
+	for _, dependency := range s.dependencies {
+		if dependency.varB != v {
+			continue
+		}
+		a := dependency.varA
+		if s.variables[a].value+common.MinStartDelay(dependency.depType, s.durations[a], s.durations[v]) == startT {
+			preds = append(preds, a)
+		}
+	}
+	for u := range s.variables {
+		if u != v && s.variables[u].value+s.durations[u] == startT && s.lacksRoomFor(u, v, startT-1) {
+			preds = append(preds, u)
+		}
+	}
+	return preds
+}
+
+func (s *Solver) CriticalTasks() []string {
+	// Tasks on a chain of binding constraints that ends at the project finish
+	makespan := 0
+	for v := range s.variables {
+		if s.variables[v].value+s.durations[v] > makespan {
+			makespan = s.variables[v].value + s.durations[v]
+		}
+	}
+	s.stockWorkspace(makespan)
+	critical := make([]bool, len(s.variables))
+	queue := []int{}
+	for v := range s.variables {
+		if s.variables[v].value+s.durations[v] == makespan {
+			critical[v] = true
+			queue = append(queue, v)
+		}
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, u := range s.bindingPredecessors(v) {
+			if !critical[u] {
+				critical[u] = true
+				queue = append(queue, u)
+			}
+		}
+	}
+	taskIds := []string{}
+	for v, isCritical := range critical {
+		if isCritical {
+			taskIds = append(taskIds, s.taskIds[v])
+		}
+	}
+	sort.Strings(taskIds)
+	return taskIds
+}
+
+func (s *Solver) chainLength(chain []int) int {
+	length := 0
+	for _, v := range chain {
+		length += s.durations[v]
+	}
+	return length
+}
+
+func (s *Solver) longestBindingChain(v int, memo map[int][]int, visiting []bool) []int {
+	chain, known := memo[v]
+	if known {
+		return chain
+	}
+	visiting[v] = true
+	best := []int{}
+	for _, u := range s.bindingPredecessors(v) {
+		if visiting[u] {
+			continue
+		}
+		candidate := s.longestBindingChain(u, memo, visiting)
+		if s.chainLength(candidate) > s.chainLength(best) {
+			best = candidate
+		}
+	}
+	visiting[v] = false
+	chain = append(append([]int{}, best...), v)
+	memo[v] = chain
+	return chain
+}
+
+func (s *Solver) CriticalChain() []string {
+	// The longest chain of binding constraints ending at the project finish
+	makespan := 0
+	for v := range s.variables {
+		if s.variables[v].value+s.durations[v] > makespan {
+			makespan = s.variables[v].value + s.durations[v]
+		}
+	}
+	s.stockWorkspace(makespan)
+	memo := map[int][]int{}
+	visiting := make([]bool, len(s.variables))
+	chain := []int{}
+	for v := range s.variables {
+		if s.variables[v].value+s.durations[v] != makespan {
+			continue
+		}
+		candidate := s.longestBindingChain(v, memo, visiting)
+		if s.chainLength(candidate) > s.chainLength(chain) {
+			chain = candidate
+		}
+	}
+	taskIds := make([]string, len(chain))
+	for i, v := range chain {
+		taskIds[i] = s.taskIds[v]
+	}
+	return taskIds
+}
+
+func (s *Solver) ResourceProfile(makespan int, schedule common.TaskSchedule) map[string][]int {
+	// Units in use of every resource along the schedule, read from the stocks left by the assignment
+	s.buildWorkspace(makespan)
+	s.resetWorkspace()
+	for taskId, startT := range schedule {
+		s.setVariable(s.varTranslations[taskId], startT)
+	}
+	profile := map[string][]int{}
+	for r, capacity := range s.capacities {
+		usage := make([]int, makespan)
+		for t := range usage {
+			usage[t] = capacity - s.stocks.GetCell(r, t)
+		}
+		profile[s.resourceIds[r]] = usage
+	}
+	return profile
+}
@@ -0,0 +1,262 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"time"
+)
+
+const (
+	BRANCH_AND_BOUND_ALGORITHM = "branch-and-bound"
+	DEFAULT_BNB_NODES          = 1000000
+)
+
+type BranchAndBoundSolver struct {
+	*Solver
+}
+
+type bnbState struct {
+	predecessors [][]dependencyConstraint
+	tails        []int
+	starts       []int
+	profile      [][]int
+	bestMakespan int
+	bestStarts   []int
+	nodes        int
+	maxNodes     int
+	deadline     time.Time
+	aborted      bool
+}
+
+func NewBranchAndBoundSolver(model common.ConstraintModel) *BranchAndBoundSolver {
+	return &BranchAndBoundSolver{NewSolver(model)}
+}
+
+func (b *BranchAndBoundSolver) tails() []int {
+	// Longest path from the start of every task to the project finish
+	tails := make([]int, len(b.variables))
+	for v := range tails {
+		tails[v] = common.UNDEF
+	}
+	var tail func(v int) int
+	tail = func(v int) int {
+		if tails[v] != common.UNDEF {
+			return tails[v]
+		}
+		tails[v] = b.durations[v]
+		for _, dependency := range b.dependencies {
+			if dependency.varA != v {
+				continue
+			}
+			u := dependency.varB
+			t := common.MinStartDelay(dependency.depType, b.durations[v], b.durations[u]) + tail(u)
+			if t > tails[v] {
+				tails[v] = t
+			}
+		}
+		return tails[v]
+	}
+	for v := range tails {
+		tail(v)
+	}
+	return tails
+}
+
+func (b *BranchAndBoundSolver) energyBound(state *bnbState, fromT int) int {
+	// Remaining work of every resource spread over its whole capacity
+	bound := 0
+	for r, capacity := range b.capacities {
+		energy := 0
+		for v, startT := range state.starts {
+			if startT == common.UNDEF {
+				energy += b.allocations.GetCell(v, r) * b.durations[v]
+			}
+		}
+		if energy > 0 && fromT+(energy+capacity-1)/capacity > bound {
+			bound = fromT + (energy+capacity-1)/capacity
+		}
+	}
+	return bound
+}
+
+func (b *BranchAndBoundSolver) releaseResources(profile [][]int, v int, startT int) {
+	for r := range b.capacities {
+		demand := b.allocations.GetCell(v, r)
+		for t := startT; t < startT+b.durations[v] && demand > 0; t++ {
+			profile[r][t] -= demand
+		}
+	}
+}
+
+func (b *BranchAndBoundSolver) earliestStart(state *bnbState, v int, lastStart int) int {
+	startT := lastStart
+	for _, dependency := range state.predecessors[v] {
+		a := dependency.varA
+		t := state.starts[a] + common.MinStartDelay(dependency.depType, b.durations[a], b.durations[v])
+		if t > startT {
+			startT = t
+		}
+	}
+	return startT
+}
+
+func (b *BranchAndBoundSolver) branch(state *bnbState, scheduled int, lastStart int, makespan int) {
+	state.nodes++
+	b.stats.Iterations++
+	if state.nodes > state.maxNodes || (state.nodes%1024 == 0 && (b.cancelled() || (!state.deadline.IsZero() && time.Now().After(state.deadline)))) {
+		state.aborted = true
+	}
+	if state.aborted {
+		return
+	}
+	if scheduled == len(b.variables) {
+		if makespan < state.bestMakespan {
+			state.bestMakespan = makespan
+			copy(state.bestStarts, state.starts)
+			b.report(makespan, b.startsSchedule(state.bestStarts))
+		}
+		return
+	}
+	bound := makespan
+	for v, startT := range state.starts {
+		if startT == common.UNDEF && b.earliestStart(state, v, lastStart)+state.tails[v] > bound {
+			bound = b.earliestStart(state, v, lastStart) + state.tails[v]
+		}
+	}
+	energyBound := b.energyBound(state, lastStart)
+	if energyBound > bound {
+		bound = energyBound
+	}
+	if b.lowerBound > bound {
+		// Nothing below the root bound, the search ends once it is met
+		bound = b.lowerBound
+	}
+	if bound >= state.bestMakespan {
+		return
+	}
+	// Precedence tree: every eligible task in turn, at its earliest feasible start not before the last one
+	eligible := []int{}
+	for v, startT := range state.starts {
+		if startT != common.UNDEF {
+			continue
+		}
+		ready := true
+		for _, dependency := range state.predecessors[v] {
+			if state.starts[dependency.varA] == common.UNDEF {
+				ready = false
+				break
+			}
+		}
+		if ready {
+			eligible = append(eligible, v)
+		}
+	}
+	for i := 1; i < len(eligible); i++ {
+		for j := i; j > 0 && b.variables[eligible[j]].minUbound < b.variables[eligible[j-1]].minUbound; j-- {
+			eligible[j], eligible[j-1] = eligible[j-1], eligible[j]
+		}
+	}
+	for _, v := range eligible {
+		startT := b.earliestStart(state, v, lastStart)
+		for startT+state.tails[v] < state.bestMakespan && !b.fitsResources(state.profile, v, startT) {
+			startT++
+		}
+		if startT+state.tails[v] >= state.bestMakespan {
+			continue
+		}
+		b.consumeResources(state.profile, v, startT)
+		state.starts[v] = startT
+		finish := makespan
+		if startT+b.durations[v] > finish {
+			finish = startT + b.durations[v]
+		}
+		b.branch(state, scheduled+1, startT, finish)
+		state.starts[v] = common.UNDEF
+		b.releaseResources(state.profile, v, startT)
+		if state.aborted {
+			return
+		}
+	}
+}
+
+func (b *BranchAndBoundSolver) startsSchedule(starts []int) common.TaskSchedule {
+	schedule := common.TaskSchedule{}
+	for v, startT := range starts {
+		schedule[b.taskIds[v]] = startT
+	}
+	return schedule
+}
+
+func (b *BranchAndBoundSolver) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	b.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	b.proven = false
+	bestMakespan, bestSchedule := b.BestScheduleGeneration(DEFAULT_SGS_SAMPLES)
+	if bestSchedule == nil {
+		return common.UNDEF, nil
+	}
+	warmMakespan, warmSchedule := b.warmStartBound()
+	if warmSchedule != nil && warmMakespan < bestMakespan {
+		bestMakespan, bestSchedule = warmMakespan, warmSchedule
+	}
+	b.report(bestMakespan, bestSchedule)
+	numVariables := len(b.variables)
+	state := bnbState{make([][]dependencyConstraint, numVariables), b.tails(), make([]int, numVariables), make([][]int, len(b.capacities)), bestMakespan, make([]int, numVariables), 0, DEFAULT_BNB_NODES, time.Time{}, false}
+	nonNegativeDelays := true
+	for _, dependency := range b.dependencies {
+		state.predecessors[dependency.varB] = append(state.predecessors[dependency.varB], dependency)
+		if common.MinStartDelay(dependency.depType, b.durations[dependency.varA], b.durations[dependency.varB]) < 0 {
+			nonNegativeDelays = false
+		}
+	}
+	for v := range state.starts {
+		state.starts[v] = common.UNDEF
+		state.bestStarts[v] = common.UNDEF
+	}
+	if b.param.maxIterations > 0 {
+		state.maxNodes = b.param.maxIterations
+	}
+	if b.param.maxTime > 0 {
+		state.deadline = time.Now().Add(time.Millisecond * time.Duration(b.param.maxTime))
+		if b.param.maxIterations <= 0 {
+			state.maxNodes = int(^uint(0) >> 1)
+		}
+	}
+	if bestMakespan > b.LowerBound() {
+		b.branch(&state, 0, 0, 0)
+	}
+	if state.bestMakespan < bestMakespan {
+		bestMakespan = state.bestMakespan
+		bestSchedule = b.startsSchedule(state.bestStarts)
+	}
+	// Without negative delays, every active schedule is enumerated and the search is a proof
+	if bestMakespan == b.lowerBound || (!state.aborted && nonNegativeDelays) {
+		b.lowerBound = bestMakespan
+		b.proven = true
+	}
+	return bestMakespan, bestSchedule
+}
+
+func (b *BranchAndBoundSolver) SolveFixedMakespan(makespan int) common.TaskSchedule {
+	bestMakespan, schedule := b.SolveOptimalMakespan()
+	if schedule == nil || bestMakespan > makespan {
+		return nil
+	}
+	return schedule
+}
@@ -0,0 +1,161 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"goproj/matrix"
+)
+
+func (s *Solver) LowerBound() int {
+	// Best of the critical path, resource workload, critical sequence and energetic reasoning bounds
+	if s.lowerBound == common.UNDEF {
+		s.lowerBound = s.workloadBound()
+		limit := s.sumTasksDurations()
+		oversized := s.hasOversizedDemand()
+		for s.lowerBound < limit && !oversized && !s.cancelled() && !(s.fitsMandatoryParts(s.lowerBound) && s.fitsEnergy(s.lowerBound)) {
+			s.lowerBound++
+		}
+	}
+	return s.lowerBound
+}
+
+func (s *Solver) Proven() bool {
+	return s.proven
+}
+
+func (s *Solver) window(v int, makespan int) (int, int) {
+	// Earliest and latest start of a task within the makespan
+	return s.variables[v].lbound, s.variables[v].minUbound + makespan - s.minMakespan
+}
+
+func (s *Solver) workloadBound() int {
+	// Work of every resource spread over its capacity, between the earliest release and the shortest tail of its tasks
+	bound := s.minMakespan
+	for r, capacity := range s.capacities {
+		energy, release, tail := 0, common.UNDEF, common.UNDEF
+		for v, x := range s.variables {
+			demand := s.allocations.GetCell(v, r)
+			if demand == 0 {
+				continue
+			}
+			energy += demand * s.durations[v]
+			if release == common.UNDEF || x.lbound < release {
+				release = x.lbound
+			}
+			if q := s.minMakespan - x.minUbound - s.durations[v]; tail == common.UNDEF || q < tail {
+				tail = q
+			}
+		}
+		if energy > 0 && capacity > 0 && release+(energy+capacity-1)/capacity+tail > bound {
+			bound = release + (energy+capacity-1)/capacity + tail
+		}
+	}
+	return bound
+}
+
+func (s *Solver) fitsMandatoryParts(makespan int) bool {
+	// Critical sequence reasoning, every task must find room next to the periods other tasks
+	// occupy wherever they start within their windows
+	usage := matrix.NewMatrix(len(s.capacities), makespan)
+	for v := range s.variables {
+		est, lst := s.window(v, makespan)
+		for t := lst; t < est+s.durations[v]; t++ {
+			for r := range s.capacities {
+				usage.SetCell(r, t, usage.GetCell(r, t)+s.allocations.GetCell(v, r))
+			}
+		}
+	}
+	for v := range s.variables {
+		est, lst := s.window(v, makespan)
+		fits := func(t int) bool {
+			for r, capacity := range s.capacities {
+				demand := s.allocations.GetCell(v, r)
+				others := usage.GetCell(r, t)
+				if t >= lst && t < est+s.durations[v] {
+					others -= demand
+				}
+				if demand > 0 && others+demand > capacity {
+					return false
+				}
+			}
+			return true
+		}
+		run, placed := 0, s.durations[v] == 0
+		for t := est; t < lst+s.durations[v] && !placed; t++ {
+			if fits(t) {
+				run++
+			} else {
+				run = 0
+			}
+			placed = run >= s.durations[v]
+		}
+		if !placed {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Solver) fitsEnergy(makespan int) bool {
+	// Energetic reasoning, the work a task does inside an interval whatever its start
+	// must not exceed the capacity of any resource over that interval
+	starts, finishes := map[int]bool{}, map[int]bool{}
+	for v := range s.variables {
+		est, lst := s.window(v, makespan)
+		starts[est], starts[lst] = true, true
+		finishes[est+s.durations[v]], finishes[lst+s.durations[v]] = true, true
+	}
+	energy := make([]int, len(s.capacities))
+	for t1 := range starts {
+		for t2 := range finishes {
+			if t2 <= t1 {
+				continue
+			}
+			for r := range energy {
+				energy[r] = 0
+			}
+			for v := range s.variables {
+				est, lst := s.window(v, makespan)
+				work := t2 - t1
+				if s.durations[v] < work {
+					work = s.durations[v]
+				}
+				if est+s.durations[v]-t1 < work {
+					work = est + s.durations[v] - t1
+				}
+				if t2-lst < work {
+					work = t2 - lst
+				}
+				if work <= 0 {
+					continue
+				}
+				for r := range energy {
+					energy[r] += work * s.allocations.GetCell(v, r)
+				}
+			}
+			for r, capacity := range s.capacities {
+				if energy[r] > capacity*(t2-t1) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+const CONSTRUCTIVE_ALGORITHM = "sgs"
+
+type ConstructiveSolver struct {
+	*Solver
+}
+
+func NewConstructiveSolver(model common.ConstraintModel) *ConstructiveSolver {
+	return &ConstructiveSolver{NewSolver(model)}
+}
+
+func (c *ConstructiveSolver) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	// Instant feasible schedule, the number of random samples follows the iterations parameter
+	samples := DEFAULT_SGS_SAMPLES
+	if c.param.maxIterations > 0 {
+		samples = c.param.maxIterations
+	}
+	c.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	makespan, schedule := c.BestScheduleGeneration(samples)
+	c.report(makespan, schedule)
+	return makespan, schedule
+}
+
+func (c *ConstructiveSolver) SolveFixedMakespan(makespan int) common.TaskSchedule {
+	bestMakespan, schedule := c.SolveOptimalMakespan()
+	if schedule == nil || bestMakespan > makespan {
+		return nil
+	}
+	return schedule
+}
@@ -0,0 +1,83 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+const (
+	BINDING_DEPENDENCY = iota
+	BINDING_RESOURCE
+)
+
+type BindingConstraint struct {
+	Kind       int
+	TaskId     string
+	DepType    int
+	ResourceId string
+	T          int
+	Holders    []string
+}
+
+func (s *Solver) resourceHolders(resIndex int, t int, excludedVar int) []string {
+	holders := []string{}
+	for v := range s.variables {
+		if v != excludedVar && s.allocations.GetCell(v, resIndex) > 0 && s.variables[v].value <= t && t < s.variables[v].value+s.durations[v] {
+			holders = append(holders, s.taskIds[v])
+		}
+	}
+	return holders
+}
+
+func (s *Solver) ExplainSchedule(makespan int, schedule common.TaskSchedule) map[string][]BindingConstraint {
+	// Attempt to start every task one time unit earlier and report the constraints that would be violated
+	s.buildWorkspace(makespan)
+	s.resetWorkspace()
+	for taskId, startT := range schedule {
+		s.setVariable(s.varTranslations[taskId], startT)
+	}
+	explanations := map[string][]BindingConstraint{}
+	for v := range s.variables {
+		x := s.variables[v].value - 1
+		if x < 0 {
+			continue
+		}
+		binding := []BindingConstraint{}
+		for _, c := range s.variables[v].constraints {
+			if c >= s.resourcesOffset {
+				continue
+			}
+			dependency := s.dependencies[c]
+			if dependency.varB == v && s.evalDependency(c, v, x) > 0 {
+				binding = append(binding, BindingConstraint{BINDING_DEPENDENCY, s.taskIds[dependency.varA], dependency.depType, "", common.UNDEF, nil})
+			}
+		}
+		for r := range s.capacities {
+			if s.allocations.GetCell(v, r) == 0 {
+				continue
+			}
+			if s.evalResources(s.resourcesOffset+r*s.makespan+x, v, x) > 0 {
+				binding = append(binding, BindingConstraint{BINDING_RESOURCE, "", common.UNDEF, s.resourceIds[r], x, s.resourceHolders(r, x, v)})
+			}
+		}
+		explanations[s.taskIds[v]] = binding
+	}
+	return explanations
+}
@@ -0,0 +1,147 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+const MAX_CRITICAL_PATHS = 10
+
+type FloatAnalysis struct {
+	TotalFloat    map[string]int
+	FreeFloat     map[string]int
+	CriticalPaths [][]string
+}
+
+func (s *Solver) latestStarts(makespan int) []int {
+	// Backward serial scheme: right-justify every task, latest finishing tasks first,
+	// once all its dependency successors are placed
+	numVariables := len(s.variables)
+	latest := make([]int, numVariables)
+	unplacedSucc := make([]int, numVariables)
+	for _, dependency := range s.dependencies {
+		unplacedSucc[dependency.varA]++
+	}
+	placed := make([]bool, numVariables)
+	profile := make([][]int, len(s.capacities))
+	for k := 0; k < numVariables; k++ {
+		v := common.UNDEF
+		for candidate := range s.variables {
+			if placed[candidate] || unplacedSucc[candidate] > 0 {
+				continue
+			}
+			if v == common.UNDEF || s.variables[candidate].value+s.durations[candidate] > s.variables[v].value+s.durations[v] {
+				v = candidate
+			}
+		}
+		if v == common.UNDEF {
+			break
+		}
+		startT := makespan - s.durations[v]
+		for _, dependency := range s.dependencies {
+			if dependency.varA != v {
+				continue
+			}
+			b := dependency.varB
+			t := latest[b] - common.MinStartDelay(dependency.depType, s.durations[v], s.durations[b])
+			if t < startT {
+				startT = t
+			}
+		}
+		for startT > s.variables[v].value && !s.fitsResources(profile, v, startT) {
+			startT--
+		}
+		s.consumeResources(profile, v, startT)
+		latest[v] = startT
+		placed[v] = true
+		for _, dependency := range s.dependencies {
+			if dependency.varB == v {
+				unplacedSucc[dependency.varA]--
+			}
+		}
+	}
+	return latest
+}
+
+func (s *Solver) freeFloat(v int, makespan int) int {
+	// Delay of v alone that leaves every other task where it is
+	value := s.variables[v].value
+	float := 0
+	for x := value + 1; x+s.durations[v] <= makespan; x++ {
+		for _, c := range s.variables[v].constraints {
+			if s.evaluate(c, v, x) > 0 {
+				return float
+			}
+		}
+		float++
+	}
+	return float
+}
+
+func (s *Solver) criticalPathsTo(v int, critical []bool, visiting []bool, suffix []int, paths *[][]string) {
+	if len(*paths) >= MAX_CRITICAL_PATHS {
+		return
+	}
+	visiting[v] = true
+	path := append([]int{v}, suffix...)
+	extended := false
+	for _, u := range s.bindingPredecessors(v) {
+		if critical[u] && !visiting[u] {
+			extended = true
+			s.criticalPathsTo(u, critical, visiting, path, paths)
+		}
+	}
+	if !extended && len(*paths) < MAX_CRITICAL_PATHS {
+		taskIds := make([]string, len(path))
+		for i, u := range path {
+			taskIds[i] = s.taskIds[u]
+		}
+		*paths = append(*paths, taskIds)
+	}
+	visiting[v] = false
+}
+
+func (s *Solver) AnalyzeFloat(makespan int, schedule common.TaskSchedule) FloatAnalysis {
+	s.buildWorkspace(makespan)
+	s.resetWorkspace()
+	for taskId, startT := range schedule {
+		s.setVariable(s.varTranslations[taskId], startT)
+	}
+	analysis := FloatAnalysis{map[string]int{}, map[string]int{}, [][]string{}}
+	latest := s.latestStarts(makespan)
+	critical := make([]bool, len(s.variables))
+	for v := range s.variables {
+		free := s.freeFloat(v, makespan)
+		total := latest[v] - s.variables[v].value
+		if total < free {
+			total = free
+		}
+		analysis.TotalFloat[s.taskIds[v]] = total
+		analysis.FreeFloat[s.taskIds[v]] = free
+		critical[v] = total == 0
+	}
+	visiting := make([]bool, len(s.variables))
+	for v := range s.variables {
+		if critical[v] && s.variables[v].value+s.durations[v] == makespan {
+			s.criticalPathsTo(v, critical, visiting, []int{}, &analysis.CriticalPaths)
+		}
+	}
+	return analysis
+}
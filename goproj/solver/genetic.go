@@ -0,0 +1,325 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"sort"
+	"sync"
+	"time"
+)
+
+const GENETIC_ALGORITHM = "genetic"
+
+const (
+	DEFAULT_GA_POPULATION  = 40
+	DEFAULT_GA_GENERATIONS = 100
+	GA_MUTATION_PERCENT    = 5
+)
+
+type individual struct {
+	list     []int
+	starts   []int
+	makespan int
+}
+
+type GeneticSolver struct {
+	*Solver
+	predecessors [][]dependencyConstraint
+	successors   [][]dependencyConstraint
+	linked       [][]bool
+}
+
+func NewGeneticSolver(model common.ConstraintModel) *GeneticSolver {
+	return &GeneticSolver{NewSolver(model), nil, nil, nil}
+}
+
+func (g *GeneticSolver) buildNetwork() {
+	numVariables := len(g.variables)
+	g.predecessors = make([][]dependencyConstraint, numVariables)
+	g.successors = make([][]dependencyConstraint, numVariables)
+	g.linked = make([][]bool, numVariables)
+	for v := range g.linked {
+		g.linked[v] = make([]bool, numVariables)
+	}
+	for _, dependency := range g.dependencies {
+		g.predecessors[dependency.varB] = append(g.predecessors[dependency.varB], dependency)
+		g.successors[dependency.varA] = append(g.successors[dependency.varA], dependency)
+		g.linked[dependency.varA][dependency.varB] = true
+		g.linked[dependency.varB][dependency.varA] = true
+	}
+}
+
+func (g *GeneticSolver) activityList(keys []int, backward bool) []int {
+	// Precedence feasible ordering of the tasks, taking the eligible task with the lowest key first.
+	// Backward lists place every task after all of its successors.
+	numVariables := len(g.variables)
+	pending := make([]int, numVariables)
+	for v := range pending {
+		if backward {
+			pending[v] = len(g.successors[v])
+		} else {
+			pending[v] = len(g.predecessors[v])
+		}
+	}
+	placed := make([]bool, numVariables)
+	list := make([]int, 0, numVariables)
+	for len(list) < numVariables {
+		v := common.UNDEF
+		for candidate := range g.variables {
+			if !placed[candidate] && pending[candidate] == 0 && (v == common.UNDEF || keys[candidate] < keys[v]) {
+				v = candidate
+			}
+		}
+		if v == common.UNDEF {
+			return nil
+		}
+		placed[v] = true
+		list = append(list, v)
+		if backward {
+			for _, dependency := range g.predecessors[v] {
+				pending[dependency.varA]--
+			}
+		} else {
+			for _, dependency := range g.successors[v] {
+				pending[dependency.varB]--
+			}
+		}
+	}
+	return list
+}
+
+func (g *GeneticSolver) decode(list []int) *individual {
+	// Serial decoding: every task in list order starts as soon as its predecessors and the resources allow
+	ind := individual{list, make([]int, len(list)), 0}
+	profile := make([][]int, len(g.capacities))
+	for _, v := range list {
+		startT := g.variables[v].lbound
+		if startT < 0 {
+			startT = 0
+		}
+		for _, dependency := range g.predecessors[v] {
+			a := dependency.varA
+			t := ind.starts[a] + common.MinStartDelay(dependency.depType, g.durations[a], g.durations[v])
+			if t > startT {
+				startT = t
+			}
+		}
+		for !g.fitsResources(profile, v, startT) {
+			startT++
+		}
+		g.consumeResources(profile, v, startT)
+		ind.starts[v] = startT
+		if startT+g.durations[v] > ind.makespan {
+			ind.makespan = startT + g.durations[v]
+		}
+	}
+	return &ind
+}
+
+func (g *GeneticSolver) justifyRight(ind *individual) []int {
+	// Backward pass of the forward-backward improvement: tasks by decreasing finish time are
+	// shifted as late as the makespan, their successors and the resources allow
+	keys := make([]int, len(ind.starts))
+	for v, startT := range ind.starts {
+		keys[v] = -(startT + g.durations[v])
+	}
+	list := g.activityList(keys, true)
+	if list == nil {
+		return nil
+	}
+	starts := make([]int, len(ind.starts))
+	profile := make([][]int, len(g.capacities))
+	for _, v := range list {
+		startT := ind.makespan - g.durations[v]
+		for _, dependency := range g.successors[v] {
+			b := dependency.varB
+			t := starts[b] - common.MinStartDelay(dependency.depType, g.durations[v], g.durations[b])
+			if t < startT {
+				startT = t
+			}
+		}
+		for startT >= 0 && !g.fitsResources(profile, v, startT) {
+			startT--
+		}
+		if startT < 0 || startT < g.variables[v].lbound {
+			return nil
+		}
+		g.consumeResources(profile, v, startT)
+		starts[v] = startT
+	}
+	return starts
+}
+
+func (g *GeneticSolver) improve(ind *individual) *individual {
+	// Forward-backward improvement, the justified list replaces the original one when no worse
+	for {
+		starts := g.justifyRight(ind)
+		if starts == nil {
+			return ind
+		}
+		list := g.activityList(starts, false)
+		if list == nil {
+			return ind
+		}
+		improved := g.decode(list)
+		if improved.makespan > ind.makespan {
+			return ind
+		}
+		if improved.makespan == ind.makespan {
+			return improved
+		}
+		ind = improved
+	}
+}
+
+func (g *GeneticSolver) evaluate(lists [][]int) []*individual {
+	// Decoding and improvement of the lists, spread over the configured threads
+	population := make([]*individual, len(lists))
+	threads := g.param.threads
+	if threads > len(lists) {
+		threads = len(lists)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(first int) {
+			defer wg.Done()
+			for k := first; k < len(lists); k += threads {
+				population[k] = g.improve(g.decode(lists[k]))
+			}
+		}(i)
+	}
+	wg.Wait()
+	g.stats.Iterations += len(lists)
+	return population
+}
+
+func (g *GeneticSolver) scheduleOf(ind *individual) common.TaskSchedule {
+	schedule := common.TaskSchedule{}
+	for v, startT := range ind.starts {
+		schedule[g.taskIds[v]] = startT
+	}
+	return schedule
+}
+
+func (g *GeneticSolver) crossover(mother []int, father []int) []int {
+	// One-point crossover: the head of the mother followed by the remaining tasks in the father's order
+	cut := g.random.Intn(len(mother) + 1)
+	child := make([]int, 0, len(mother))
+	taken := make([]bool, len(mother))
+	for _, v := range mother[:cut] {
+		child = append(child, v)
+		taken[v] = true
+	}
+	for _, v := range father {
+		if !taken[v] {
+			child = append(child, v)
+		}
+	}
+	return child
+}
+
+func (g *GeneticSolver) mutate(list []int) {
+	// Swaps neighbours that are not linked by a dependency, which keeps the list precedence feasible
+	for i := 0; i+1 < len(list); i++ {
+		if g.random.Intn(100) < GA_MUTATION_PERCENT && !g.linked[list[i]][list[i+1]] {
+			list[i], list[i+1] = list[i+1], list[i]
+		}
+	}
+}
+
+func (g *GeneticSolver) initialLists(size int) [][]int {
+	lists := [][]int{}
+	if g.initial != nil {
+		if list := g.activityList(g.initial, false); list != nil {
+			lists = append(lists, list)
+		}
+	}
+	for _, rule := range []int{RULE_LST, RULE_LFT, RULE_MTS, RULE_GRPW} {
+		if list := g.activityList(g.Priorities(rule), false); list != nil {
+			lists = append(lists, list)
+		}
+	}
+	for len(lists) < size {
+		list := g.activityList(g.Priorities(RULE_RANDOM), false)
+		if list == nil {
+			return nil
+		}
+		lists = append(lists, list)
+	}
+	return lists
+}
+
+func (g *GeneticSolver) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	g.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	if len(g.variables) == 0 || g.hasOversizedDemand() {
+		return common.UNDEF, nil
+	}
+	g.buildNetwork()
+	lists := g.initialLists(DEFAULT_GA_POPULATION)
+	if lists == nil {
+		return common.UNDEF, nil
+	}
+	population := g.evaluate(lists)
+	generations := DEFAULT_GA_GENERATIONS
+	if g.param.maxIterations > 0 {
+		generations = g.param.maxIterations
+	}
+	var deadline time.Time
+	if g.param.maxTime > 0 {
+		deadline = time.Now().Add(time.Millisecond * time.Duration(g.param.maxTime))
+	}
+	byMakespan := func(population []*individual) {
+		sort.SliceStable(population, func(i, j int) bool {
+			return population[i].makespan < population[j].makespan
+		})
+	}
+	byMakespan(population)
+	lowerBound := g.LowerBound()
+	for generation := 0; generation < generations; generation++ {
+		g.report(population[0].makespan, g.scheduleOf(population[0]))
+		if population[0].makespan <= lowerBound || g.cancelled() || (!deadline.IsZero() && time.Now().After(deadline)) {
+			break
+		}
+		// Random pairs of parents breed two children each, the best of parents and children survive
+		order := g.random.Perm(len(population))
+		children := [][]int{}
+		for i := 0; i+1 < len(order); i += 2 {
+			mother, father := population[order[i]].list, population[order[i+1]].list
+			for _, child := range [][]int{g.crossover(mother, father), g.crossover(father, mother)} {
+				g.mutate(child)
+				children = append(children, child)
+			}
+		}
+		population = append(population, g.evaluate(children)...)
+		byMakespan(population)
+		population = population[:len(lists)]
+	}
+	g.report(population[0].makespan, g.scheduleOf(population[0]))
+	return population[0].makespan, g.scheduleOf(population[0])
+}
+
+func (g *GeneticSolver) SolveFixedMakespan(makespan int) common.TaskSchedule {
+	bestMakespan, schedule := g.SolveOptimalMakespan()
+	if schedule == nil || bestMakespan > makespan {
+		return nil
+	}
+	return schedule
+}
@@ -0,0 +1,199 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"time"
+)
+
+const LNS_ALGORITHM = "lns"
+
+const (
+	TIME_WINDOW_NEIGHBOURHOOD = iota
+	RELATED_TASKS_NEIGHBOURHOOD
+)
+
+const (
+	DEFAULT_LNS_ROUNDS         = 200
+	DEFAULT_LNS_SUB_ITERATIONS = 500
+	LNS_MIN_PERCENT            = 20
+	LNS_PERCENT_STEP           = 10
+)
+
+type LNSSolver struct {
+	*Solver
+	neighbours [][]int
+}
+
+func NewLNSSolver(model common.ConstraintModel) *LNSSolver {
+	return &LNSSolver{NewSolver(model), nil}
+}
+
+func (s *Solver) isFixed(v int) bool {
+	return s.variables[v].lbound == s.variables[v].ubound || (s.fixed != nil && s.fixed[v])
+}
+
+func (l *LNSSolver) related(a int, b int, starts []int) bool {
+	// Tasks sharing a resource are related when they run next to each other
+	return l.sharesResource(a, b) && starts[a] <= starts[b]+l.durations[b] && starts[b] <= starts[a]+l.durations[a]
+}
+
+func (l *LNSSolver) neighbourhood(kind int, starts []int, makespan int, percent int) []bool {
+	numVariables := len(starts)
+	size := (numVariables*percent + 99) / 100
+	free := make([]bool, numVariables)
+	count := 0
+	release := func(v int) {
+		if !free[v] {
+			free[v] = true
+			count++
+		}
+	}
+	switch kind {
+	case TIME_WINDOW_NEIGHBOURHOOD:
+		// Every task running within a random window, whose width follows the neighbourhood size
+		width := (makespan*percent + 99) / 100
+		t0 := l.random.Intn(makespan - width + 1)
+		for v, startT := range starts {
+			if startT < t0+width && startT+l.durations[v] > t0 {
+				release(v)
+			}
+		}
+	case RELATED_TASKS_NEIGHBOURHOOD:
+		// Tasks reached from random seeds through dependencies and shared resources
+		for count < size {
+			queue := []int{l.random.Intn(numVariables)}
+			for len(queue) > 0 && count < size {
+				v := queue[0]
+				queue = queue[1:]
+				if free[v] {
+					continue
+				}
+				release(v)
+				queue = append(queue, l.neighbours[v]...)
+				for u := range starts {
+					if !free[u] && u != v && l.related(v, u, starts) {
+						queue = append(queue, u)
+					}
+				}
+			}
+		}
+	}
+	// Tasks starting too late for the target makespan must move whatever the neighbourhood
+	slack := makespan - l.minMakespan
+	for v, startT := range starts {
+		if startT > l.variables[v].minUbound+slack {
+			release(v)
+		}
+	}
+	fixed := make([]bool, numVariables)
+	for v := range fixed {
+		fixed[v] = !free[v]
+	}
+	return fixed
+}
+
+func (l *LNSSolver) solve(target int) (int, common.TaskSchedule) {
+	// Starting from a constructive schedule, every round frees part of the tasks and looks for a
+	// schedule one period shorter with the local search, the other tasks keeping their starts
+	l.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	bestMakespan, bestSchedule := l.BestScheduleGeneration(DEFAULT_SGS_SAMPLES)
+	warmMakespan, warmSchedule := l.warmStartBound()
+	if warmSchedule != nil && (bestSchedule == nil || warmMakespan < bestMakespan) {
+		bestMakespan, bestSchedule = warmMakespan, warmSchedule
+	}
+	if bestSchedule == nil {
+		return common.UNDEF, nil
+	}
+	l.report(bestMakespan, bestSchedule)
+	stats := l.stats
+	l.neighbours = make([][]int, len(l.variables))
+	for _, dependency := range l.dependencies {
+		l.neighbours[dependency.varA] = append(l.neighbours[dependency.varA], dependency.varB)
+		l.neighbours[dependency.varB] = append(l.neighbours[dependency.varB], dependency.varA)
+	}
+	param, initial := l.param, l.initial
+	var deadline time.Time
+	if param.maxTime > 0 {
+		deadline = time.Now().Add(time.Millisecond * time.Duration(param.maxTime))
+	}
+	l.param.maxTime = DEFAULT_MAX_TIME
+	if param.maxIterations <= 0 {
+		l.param.maxIterations = DEFAULT_LNS_SUB_ITERATIONS
+	}
+	percent := LNS_MIN_PERCENT
+	lowerBound := l.LowerBound()
+	for round := 0; round < DEFAULT_LNS_ROUNDS || !deadline.IsZero(); round++ {
+		if bestMakespan <= target || bestMakespan <= lowerBound || l.cancelled() || (!deadline.IsZero() && time.Now().After(deadline)) {
+			break
+		}
+		// Below the best makespan known, which other portfolio members may have found
+		makespan := l.boundedBy(bestMakespan) - 1
+		if makespan < lowerBound {
+			break
+		}
+		l.initial = make([]int, len(l.variables))
+		for taskId, startT := range bestSchedule {
+			l.initial[l.varTranslations[taskId]] = startT
+		}
+		l.fixed = l.neighbourhood(round%2, l.initial, makespan, percent)
+		schedule := l.Solver.SolveFixedMakespan(makespan)
+		stats.Iterations += l.stats.Iterations
+		stats.Assignments += l.stats.Assignments
+		stats.Restarts += l.stats.Restarts
+		stats.Escapes += l.stats.Escapes
+		if schedule == nil {
+			// Widen the neighbourhood until the search no longer stalls, without a time limit
+			// there is no point in repeating a failed search over every task
+			if percent >= 100 && deadline.IsZero() {
+				break
+			}
+			percent += LNS_PERCENT_STEP
+			if percent > 100 {
+				percent = 100
+			}
+			continue
+		}
+		bestMakespan = 0
+		for v, x := range l.variables {
+			if x.value+l.durations[v] > bestMakespan {
+				bestMakespan = x.value + l.durations[v]
+			}
+		}
+		bestSchedule = schedule
+		percent = LNS_MIN_PERCENT
+		l.report(bestMakespan, bestSchedule)
+	}
+	l.param, l.initial, l.fixed = param, initial, nil
+	l.stats = stats
+	return bestMakespan, bestSchedule
+}
+
+func (l *LNSSolver) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	return l.solve(l.minMakespan)
+}
+
+func (l *LNSSolver) SolveFixedMakespan(makespan int) common.TaskSchedule {
+	bestMakespan, schedule := l.solve(makespan)
+	if schedule == nil || bestMakespan > makespan {
+		return nil
+	}
+	return schedule
+}
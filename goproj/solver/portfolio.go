@@ -0,0 +1,313 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const PORTFOLIO_ALGORITHM = "portfolio"
+
+const (
+	PORTFOLIO_FIRST = "first"
+	PORTFOLIO_BEST  = "best"
+)
+
+type PortfolioMember struct {
+	Algorithm string
+	Threads   int
+	Step      int
+	Seed      int64 // Zero leaves the member searching anew on every run
+}
+
+var defaultPortfolio = []PortfolioMember{
+	{DEFAULT_ALGORITHM, 0, 0, 0},
+	{LNS_ALGORITHM, 0, 0, 0},
+	{GENETIC_ALGORITHM, 0, 0, 0},
+}
+
+type SharedBound struct {
+	mutex    sync.Mutex
+	makespan int
+	done     bool
+}
+
+type SharedAlgorithm interface {
+	SetSharedBound(bound *SharedBound)
+}
+
+type memberResult struct {
+	member   int
+	makespan int
+	schedule common.TaskSchedule
+	solver   Algorithm
+}
+
+type PortfolioSolver struct {
+	model      common.ConstraintModel
+	members    []PortfolioMember
+	mode       string
+	param      parameters
+	reference  common.TaskSchedule
+	initial    common.TaskSchedule
+	stats      Statistics
+	lowerBound int
+	proven     bool
+	ctx        context.Context
+	progress   ProgressFunc
+	seeded     bool
+}
+
+func NewSharedBound() *SharedBound {
+	return &SharedBound{sync.Mutex{}, common.UNDEF, false}
+}
+
+func (b *SharedBound) Offer(makespan int) {
+	b.mutex.Lock()
+	if makespan > 0 && (b.makespan == common.UNDEF || makespan < b.makespan) {
+		b.makespan = makespan
+	}
+	b.mutex.Unlock()
+}
+
+func (b *SharedBound) Makespan() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.makespan
+}
+
+func (b *SharedBound) Finish() {
+	b.mutex.Lock()
+	b.done = true
+	b.mutex.Unlock()
+}
+
+func (b *SharedBound) Done() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.done
+}
+
+func (s *Solver) SetSharedBound(bound *SharedBound) {
+	s.shared = bound
+}
+
+func (s *Solver) boundedBy(makespan int) int {
+	// The tighter of the given makespan and the best one found by the other portfolio members
+	if s.shared != nil {
+		shared := s.shared.Makespan()
+		if shared > common.UNDEF && shared < makespan {
+			return shared
+		}
+	}
+	return makespan
+}
+
+func (s *Solver) cancelled() bool {
+	return s.interrupted() || (s.shared != nil && s.shared.Done())
+}
+
+func CheckPortfolio(members []PortfolioMember, mode string, seeded bool) string {
+	// Members share their best makespan as soon as they find it, which no seed can repeat
+	if seeded {
+		return "A seeded search cannot run a portfolio"
+	}
+	if mode != "" && mode != PORTFOLIO_FIRST && mode != PORTFOLIO_BEST {
+		return fmt.Sprintf("Unknown portfolio mode '%s'", mode)
+	}
+	for _, member := range members {
+		if member.Algorithm == PORTFOLIO_ALGORITHM {
+			return "A portfolio cannot be a member of another portfolio"
+		}
+		if !HasAlgorithm(member.Algorithm) {
+			return fmt.Sprintf("Unknown solver algorithm '%s'", member.Algorithm)
+		}
+	}
+	return ""
+}
+
+func NewPortfolioSolver(model common.ConstraintModel) *PortfolioSolver {
+	param := parameters{DEFAULT_MAX_ITERATIONS, DEFAULT_THREADS, DEFAULT_STEP, DEFAULT_MAX_TIME}
+	return &PortfolioSolver{model, defaultPortfolio, PORTFOLIO_BEST, param, nil, nil, Statistics{common.UNDEF, 0, 0, 0, 0}, common.UNDEF, false, nil, nil, false}
+}
+
+func (p *PortfolioSolver) SetMembers(members []PortfolioMember, mode string) string {
+	err := CheckPortfolio(members, mode, p.seeded)
+	if err != "" {
+		return err
+	}
+	if len(members) > 0 {
+		p.members = members
+	}
+	if mode != "" {
+		p.mode = mode
+	}
+	return ""
+}
+
+func (p *PortfolioSolver) SetParameters(maxIterations int, threads int, step int, maxTime int) {
+	p.param = parameters{maxIterations, threads, step, maxTime}
+}
+
+func (p *PortfolioSolver) SetReference(schedule common.TaskSchedule) {
+	p.reference = schedule
+}
+
+func (p *PortfolioSolver) SetInitialAssignment(schedule common.TaskSchedule) {
+	p.initial = schedule
+}
+
+func (p *PortfolioSolver) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+func (p *PortfolioSolver) SetSeed(seed int64) {
+	// Only recorded, so that the members are then refused
+	p.seeded = true
+}
+
+func (p *PortfolioSolver) SetProgress(callback ProgressFunc) {
+	p.progress = callback
+}
+
+func (p *PortfolioSolver) Stats() Statistics {
+	return p.stats
+}
+
+func (p *PortfolioSolver) LowerBound() int {
+	return p.lowerBound
+}
+
+func (p *PortfolioSolver) Proven() bool {
+	return p.proven
+}
+
+func (p *PortfolioSolver) run(solve func(a Algorithm) (int, common.TaskSchedule), firstFound bool) (int, common.TaskSchedule) {
+	// Every member solves the model concurrently, sharing the best makespan found so far
+	if CheckPortfolio(p.members, p.mode, p.seeded) != "" {
+		return common.UNDEF, nil
+	}
+	bound := NewSharedBound()
+	results := make(chan memberResult, len(p.members))
+	// Only schedules improving on every member so far are reported, and none once the portfolio returns
+	var mutexProgress sync.Mutex
+	reported, closed, start := common.UNDEF, false, time.Now()
+	defer func() {
+		mutexProgress.Lock()
+		closed = true
+		mutexProgress.Unlock()
+	}()
+	memberProgress := func(progress Progress) {
+		mutexProgress.Lock()
+		defer mutexProgress.Unlock()
+		if !closed && (reported == common.UNDEF || progress.Makespan < reported) {
+			reported = progress.Makespan
+			progress.Elapsed = time.Since(start)
+			p.progress(progress)
+		}
+	}
+	for i, member := range p.members {
+		a, _ := NewAlgorithm(member.Algorithm, p.model)
+		threads, step := member.Threads, member.Step
+		if threads <= 0 {
+			threads = 1 // Members run side by side instead of splitting one search across threads
+		}
+		if step <= 0 {
+			step = p.param.step
+		}
+		a.SetParameters(p.param.maxIterations, threads, step, p.param.maxTime)
+		if p.reference != nil {
+			a.SetReference(p.reference)
+		}
+		if p.initial != nil {
+			a.SetInitialAssignment(p.initial)
+		}
+		seeded, isSeeded := a.(SeededAlgorithm)
+		if isSeeded && member.Seed != 0 {
+			seeded.SetSeed(member.Seed)
+		}
+		shared, isShared := a.(SharedAlgorithm)
+		if isShared {
+			shared.SetSharedBound(bound)
+		}
+		contextual, isContextual := a.(ContextAlgorithm)
+		if isContextual && p.ctx != nil {
+			contextual.SetContext(p.ctx)
+		}
+		progressive, isProgressive := a.(ProgressAlgorithm)
+		if isProgressive && p.progress != nil {
+			progressive.SetProgress(memberProgress)
+		}
+		go func(i int, a Algorithm) {
+			makespan, schedule := solve(a)
+			if schedule != nil {
+				bound.Offer(makespan)
+			}
+			results <- memberResult{i, makespan, schedule, a}
+		}(i, a)
+	}
+	p.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	p.lowerBound, p.proven = common.UNDEF, false
+	var best *memberResult
+	for k := 0; k < len(p.members); k++ {
+		result := <-results
+		stats := result.solver.Stats()
+		p.stats.Iterations += stats.Iterations
+		p.stats.Assignments += stats.Assignments
+		p.stats.Restarts += stats.Restarts
+		p.stats.Escapes += stats.Escapes
+		bounded, isBounded := result.solver.(BoundedAlgorithm)
+		if isBounded && bounded.LowerBound() > p.lowerBound {
+			p.lowerBound = bounded.LowerBound()
+		}
+		if result.schedule != nil && (best == nil || result.makespan < best.makespan || (result.makespan == best.makespan && result.member < best.member)) {
+			best = &result
+		}
+		if best != nil && (firstFound || p.mode == PORTFOLIO_FIRST) {
+			// The remaining members stop at their next check
+			bound.Finish()
+			break
+		}
+	}
+	bound.Finish()
+	if best == nil {
+		return common.UNDEF, nil
+	}
+	p.proven = best.makespan == p.lowerBound
+	return best.makespan, best.schedule
+}
+
+func (p *PortfolioSolver) SolveOptimalMakespan() (int, common.TaskSchedule) {
+	return p.run(func(a Algorithm) (int, common.TaskSchedule) {
+		return a.SolveOptimalMakespan()
+	}, false)
+}
+
+func (p *PortfolioSolver) SolveFixedMakespan(makespan int) common.TaskSchedule {
+	// Any member meeting the makespan settles the question
+	_, schedule := p.run(func(a Algorithm) (int, common.TaskSchedule) {
+		schedule := a.SolveFixedMakespan(makespan)
+		return makespan, schedule
+	}, true)
+	return schedule
+}
@@ -0,0 +1,62 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+	"time"
+)
+
+type Progress struct {
+	Makespan   int
+	Elapsed    time.Duration
+	Iterations int
+	Schedule   common.TaskSchedule
+}
+
+type ProgressFunc func(progress Progress)
+
+type ProgressAlgorithm interface {
+	SetProgress(callback ProgressFunc)
+}
+
+type progressState struct {
+	callback   ProgressFunc
+	start      time.Time
+	makespan   int
+	iterations int
+}
+
+func (s *Solver) SetProgress(callback ProgressFunc) {
+	// Every improved schedule is reported from now on, with the time elapsed since this call
+	s.progress = &progressState{callback, time.Now(), common.UNDEF, 0}
+}
+
+func (s *Solver) report(makespan int, schedule common.TaskSchedule) {
+	// A feasible schedule was found, shared with the portfolio and announced when it improves
+	if s.shared != nil {
+		s.shared.Offer(makespan)
+	}
+	if s.progress == nil || schedule == nil || (s.progress.makespan != common.UNDEF && makespan >= s.progress.makespan) {
+		return
+	}
+	s.progress.makespan = makespan
+	// Iterations of the searches already finished, which reset their statistics, plus the current one
+	s.progress.callback(Progress{makespan, time.Since(s.progress.start), s.progress.iterations + s.stats.Iterations, schedule})
+}
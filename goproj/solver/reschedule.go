@@ -0,0 +1,161 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+func (s *Solver) SetReference(schedule common.TaskSchedule) {
+	// Start offsets of a previous schedule the search should stay close to
+	s.reference = make([]int, len(s.variables))
+	for v := range s.reference {
+		s.reference[v] = common.UNDEF
+	}
+	for taskId, startT := range schedule {
+		v, exists := s.varTranslations[taskId]
+		if exists {
+			s.reference[v] = startT
+		}
+	}
+}
+
+func (s *Solver) SetInitialAssignment(schedule common.TaskSchedule) {
+	// Start offsets the local search begins with, and a makespan bound when they are feasible
+	s.initial = make([]int, len(s.variables))
+	for v := range s.initial {
+		s.initial[v] = common.UNDEF
+	}
+	for taskId, startT := range schedule {
+		v, exists := s.varTranslations[taskId]
+		if exists {
+			s.initial[v] = startT
+		}
+	}
+}
+
+func (s *Solver) hasReference(v int) bool {
+	return s.reference != nil && s.reference[v] > common.UNDEF
+}
+
+func (s *Solver) initialValue(v int) int {
+	lbound := s.variables[v].lbound
+	ubound := s.variables[v].ubound
+	value := common.UNDEF
+	if s.fixed != nil && s.fixed[v] {
+		return s.initial[v]
+	}
+	if s.hasReference(v) {
+		value = s.reference[v]
+	} else if s.initial != nil && s.initial[v] > common.UNDEF {
+		value = s.initial[v]
+	} else {
+		return lbound + s.random.Intn(ubound-lbound+1)
+	}
+	if value < lbound {
+		value = lbound
+	}
+	if value > ubound {
+		value = ubound
+	}
+	return value
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func (s *Solver) deviationChange(v int, x int) int {
+	if !s.hasReference(v) {
+		return 0
+	}
+	return abs(x-s.reference[v]) - abs(s.variables[v].value-s.reference[v])
+}
+
+func (s *Solver) feasibleMove(v int, x int) bool {
+	for _, c := range s.variables[v].constraints {
+		if s.evaluate(c, v, x) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Solver) pullTowardsReference() {
+	// Greedily move every task of a feasible assignment to the feasible value closest
+	// to its reference, until no task can get any closer
+	changed := true
+	for changed {
+		changed = false
+		for v := range s.variables {
+			if !s.hasReference(v) || s.variables[v].value == s.reference[v] {
+				continue
+			}
+			value := s.variables[v].value
+			target := s.reference[v]
+			if target < s.variables[v].lbound {
+				target = s.variables[v].lbound
+			}
+			if target > s.makespan-s.durations[v] {
+				target = s.makespan - s.durations[v]
+			}
+			step := 1
+			if target > value {
+				step = -1
+			}
+			for x := target; x != value; x += step {
+				if s.feasibleMove(v, x) {
+					s.setVariable(v, x)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+func (s *Solver) warmStartBound() (int, common.TaskSchedule) {
+	// Makespan of the initial assignment when it is complete and satisfies every constraint
+	if s.initial == nil {
+		return common.UNDEF, nil
+	}
+	makespan := 0
+	for v, startT := range s.initial {
+		if startT < 0 {
+			return common.UNDEF, nil
+		}
+		if startT+s.durations[v] > makespan {
+			makespan = startT + s.durations[v]
+		}
+	}
+	s.buildWorkspace(makespan)
+	s.resetWorkspace()
+	for v, startT := range s.initial {
+		s.setVariable(v, startT)
+	}
+	for c := range s.constraints {
+		if s.evaluate(c, common.UNDEF, common.UNDEF) > 0 {
+			return common.UNDEF, nil
+		}
+	}
+	return makespan, s.ExportSolution()
+}
@@ -0,0 +1,242 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+const (
+	SERIAL_SGS = iota
+	PARALLEL_SGS
+)
+
+const (
+	RULE_LST = iota
+	RULE_LFT
+	RULE_MTS
+	RULE_GRPW
+	RULE_RANDOM
+)
+
+const DEFAULT_SGS_SAMPLES = 20
+
+func (s *Solver) fitsResources(profile [][]int, varIndex int, startT int) bool {
+	for r, capacity := range s.capacities {
+		demand := s.allocations.GetCell(varIndex, r)
+		if demand == 0 {
+			continue
+		}
+		for t := startT; t < startT+s.durations[varIndex] && t < len(profile[r]); t++ {
+			if profile[r][t]+demand > capacity {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *Solver) consumeResources(profile [][]int, varIndex int, startT int) {
+	finishT := startT + s.durations[varIndex]
+	for r := range s.capacities {
+		demand := s.allocations.GetCell(varIndex, r)
+		if demand == 0 {
+			continue
+		}
+		for len(profile[r]) < finishT {
+			profile[r] = append(profile[r], 0)
+		}
+		for t := startT; t < finishT; t++ {
+			profile[r][t] += demand
+		}
+	}
+}
+
+func (s *Solver) hasOversizedDemand() bool {
+	for v := range s.variables {
+		for r, capacity := range s.capacities {
+			if s.allocations.GetCell(v, r) > capacity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Solver) Priorities(rule int) []int {
+	// Lower values are scheduled first
+	numVariables := len(s.variables)
+	priorities := make([]int, numVariables)
+	successors := make([][]int, numVariables)
+	for _, dependency := range s.dependencies {
+		successors[dependency.varA] = append(successors[dependency.varA], dependency.varB)
+	}
+	if rule == RULE_RANDOM {
+		return s.random.Perm(numVariables)
+	}
+	for v := range s.variables {
+		switch rule {
+		case RULE_LST:
+			priorities[v] = s.variables[v].minUbound
+		case RULE_LFT:
+			priorities[v] = s.variables[v].minUbound + s.durations[v]
+		case RULE_MTS:
+			// Most total successors, direct or not
+			reached := make([]bool, numVariables)
+			queue := append([]int{}, successors[v]...)
+			for len(queue) > 0 {
+				u := queue[0]
+				queue = queue[1:]
+				if reached[u] {
+					continue
+				}
+				reached[u] = true
+				priorities[v]--
+				queue = append(queue, successors[u]...)
+			}
+		case RULE_GRPW:
+			// Greatest rank positional weight: own duration plus the durations of direct successors
+			priorities[v] = -s.durations[v]
+			for _, u := range successors[v] {
+				priorities[v] -= s.durations[u]
+			}
+		}
+	}
+	return priorities
+}
+
+func (s *Solver) earliestDependencyStart(predecessors []dependencyConstraint, v int) int {
+	startT := 0
+	for _, dependency := range predecessors {
+		a := dependency.varA
+		t := s.variables[a].value + common.MinStartDelay(dependency.depType, s.durations[a], s.durations[v])
+		if t > startT {
+			startT = t
+		}
+	}
+	return startT
+}
+
+func (s *Solver) ScheduleGeneration(scheme int, priorities []int) (int, common.TaskSchedule) {
+	// Serial scheme: pick the eligible task with the best priority and start it as soon as possible.
+	// Parallel scheme: advance the time and start every eligible task that fits, by priority.
+	numVariables := len(s.variables)
+	if s.hasOversizedDemand() {
+		return common.UNDEF, nil
+	}
+	predecessors := make([][]dependencyConstraint, numVariables)
+	unscheduledPred := make([]int, numVariables)
+	for _, dependency := range s.dependencies {
+		predecessors[dependency.varB] = append(predecessors[dependency.varB], dependency)
+		unscheduledPred[dependency.varB]++
+	}
+	scheduled := make([]bool, numVariables)
+	profile := make([][]int, len(s.capacities))
+	makespan := 0
+	schedule := func(v int, startT int) {
+		s.consumeResources(profile, v, startT)
+		s.variables[v].value = startT
+		scheduled[v] = true
+		for _, dependency := range s.dependencies {
+			if dependency.varA == v {
+				unscheduledPred[dependency.varB]--
+			}
+		}
+		if startT+s.durations[v] > makespan {
+			makespan = startT + s.durations[v]
+		}
+	}
+	t := 0
+	for k := 0; k < numVariables; {
+		v := common.UNDEF
+		nextT := common.UNDEF
+		for candidate := range s.variables {
+			if scheduled[candidate] || unscheduledPred[candidate] > 0 {
+				continue
+			}
+			if scheme == PARALLEL_SGS {
+				est := s.earliestDependencyStart(predecessors[candidate], candidate)
+				if est > t || !s.fitsResources(profile, candidate, t) {
+					if est > t && (nextT == common.UNDEF || est < nextT) {
+						nextT = est
+					}
+					continue
+				}
+			}
+			if v == common.UNDEF || priorities[candidate] < priorities[v] {
+				v = candidate
+			}
+		}
+		if v != common.UNDEF {
+			startT := t
+			if scheme == SERIAL_SGS {
+				startT = s.earliestDependencyStart(predecessors[v], v)
+				for !s.fitsResources(profile, v, startT) {
+					startT++
+				}
+			}
+			schedule(v, startT)
+			k++
+			continue
+		}
+		if scheme == SERIAL_SGS {
+			// No eligible task left, dependencies must be cyclic
+			return common.UNDEF, nil
+		}
+		for u := range s.variables {
+			finishT := s.variables[u].value + s.durations[u]
+			if scheduled[u] && finishT > t && (nextT == common.UNDEF || finishT < nextT) {
+				nextT = finishT
+			}
+		}
+		if nextT == common.UNDEF {
+			return common.UNDEF, nil
+		}
+		t = nextT
+	}
+	return makespan, s.ExportSolution()
+}
+
+func (s *Solver) SerialSchedule() (int, common.TaskSchedule) {
+	// Serial schedule generation scheme, giving priority to the task with the lowest latest start
+	return s.ScheduleGeneration(SERIAL_SGS, s.Priorities(RULE_LST))
+}
+
+func (s *Solver) BestScheduleGeneration(samples int) (int, common.TaskSchedule) {
+	// Both schemes under every priority rule, plus random priority samples, keeping the shortest schedule
+	bestMakespan := common.UNDEF
+	var bestSchedule common.TaskSchedule
+	for _, scheme := range []int{SERIAL_SGS, PARALLEL_SGS} {
+		for _, rule := range []int{RULE_LST, RULE_LFT, RULE_MTS, RULE_GRPW, RULE_RANDOM} {
+			passes := 1
+			if rule == RULE_RANDOM {
+				passes = samples
+			}
+			for pass := 0; pass < passes; pass++ {
+				makespan, schedule := s.ScheduleGeneration(scheme, s.Priorities(rule))
+				s.stats.Iterations++
+				if schedule != nil && (bestSchedule == nil || makespan < bestMakespan) {
+					bestMakespan = makespan
+					bestSchedule = schedule
+				}
+			}
+		}
+	}
+	return bestMakespan, bestSchedule
+}
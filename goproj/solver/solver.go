@@ -21,8 +21,10 @@ package solver
 import (
 	"goproj/common"
 	"goproj/matrix"
+	"context"
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 )
@@ -77,10 +79,24 @@ type Solver struct {
 	capacities      []int
 	allocations     matrix.Matrix
 	varTranslations map[string]int
+	taskIds         []string
+	resourceIds     []string
+	reference       []int
+	initial         []int
+	fixed           []bool
+	tabu            []map[int]int
+	rawScore        int
+	bestRawScore    int
+	shared          *SharedBound
+	ctx             context.Context
+	progress        *progressState
+	random          *rand.Rand
 	variables       []variable
 	stocks          matrix.Matrix
 	makespan        int
 	minMakespan     int
+	lowerBound      int
+	proven          bool
 	resourcesOffset int
 	constraints     []constraint
 	varChannels     []chan int
@@ -88,7 +104,7 @@ type Solver struct {
 	mutexVar        sync.Mutex
 	mutexStop       sync.Mutex
 	nextVar         int
-	stop            bool
+	stopVar         int
 	model           common.ConstraintModel
 	param           parameters
 	stats           Statistics
@@ -97,6 +113,9 @@ type Solver struct {
 func NewSolver(model common.ConstraintModel) *Solver {
 	var s Solver
 	s.param = parameters{DEFAULT_MAX_ITERATIONS, DEFAULT_THREADS, DEFAULT_STEP, DEFAULT_MAX_TIME}
+	s.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
+	s.lowerBound = common.UNDEF
+	s.random = rand.New(rand.NewSource(time.Now().UnixNano()))
 	s.importConstraintModel(model)
 	return &s
 }
@@ -125,33 +144,37 @@ func (s *Solver) SetParameters(maxIterations int, threads int, step int, maxTime
 }
 
 func (s *Solver) importConstraintModel(model common.ConstraintModel) {
+	// Variables, resources and dependencies follow the sorted ids, so that a seeded search is repeatable
 	s.varTranslations = map[string]int{}
+	s.taskIds = []string{}
+	for taskId := range model.TaskDefinitions {
+		s.taskIds = append(s.taskIds, taskId)
+	}
+	sort.Strings(s.taskIds)
 	s.variables = make([]variable, len(model.TaskDefinitions))
 	s.durations = make([]int, len(model.TaskDefinitions))
-	id := 0
-	for taskId, task := range model.TaskDefinitions {
+	for id, taskId := range s.taskIds {
+		task := model.TaskDefinitions[taskId]
 		s.varTranslations[taskId] = id
 		s.durations[id] = task.Duration
 		s.variables[id].lbound = task.EarliestStart
 		s.variables[id].minUbound = task.LatestStart
 		s.variables[id].ubound = s.variables[id].minUbound // Default value, will vary along the search process
 		s.variables[id].constraints = []int{}
-		id++
 	}
 	resourceTranslation := map[string]int{}
+	s.resourceIds = sortedIds(model.ResourceDefinitions)
 	s.capacities = make([]int, len(model.ResourceDefinitions))
-	id = 0
-	for resourceId, capacity := range model.ResourceDefinitions {
+	for id, resourceId := range s.resourceIds {
 		resourceTranslation[resourceId] = id
-		s.capacities[id] = capacity
-		id++
+		s.capacities[id] = model.ResourceDefinitions[resourceId]
 	}
 	s.dependencies = []dependencyConstraint{}
-	for idTask1, dependency := range model.TaskDependencies {
+	for _, idTask1 := range s.taskIds {
 		a := s.varTranslations[idTask1]
-		for idTask2, depType := range dependency {
+		for _, idTask2 := range sortedIds(model.TaskDependencies[idTask1]) {
 			b := s.varTranslations[idTask2]
-			s.dependencies = append(s.dependencies, dependencyConstraint{a, b, depType})
+			s.dependencies = append(s.dependencies, dependencyConstraint{a, b, model.TaskDependencies[idTask1][idTask2]})
 		}
 	}
 	s.allocations = *matrix.NewMatrix(len(s.variables), len(s.capacities))
@@ -166,6 +189,15 @@ func (s *Solver) importConstraintModel(model common.ConstraintModel) {
 	s.model = model
 }
 
+func sortedIds(levels map[string]int) []string {
+	ids := []string{}
+	for id := range levels {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 func (s *Solver) buildWorkspace(makeSpan int) {
 	s.stocks = *matrix.NewMatrix(len(s.capacities), makeSpan)
 	for i, value := range s.capacities {
@@ -212,6 +244,9 @@ func (s *Solver) resetWorkspace() {
 		s.constraints[i].score = 0
 		s.constraints[i].weight = 1
 	}
+	if s.progress != nil {
+		s.progress.iterations += s.stats.Iterations
+	}
 	s.stats = Statistics{common.UNDEF, 0, 0, 0, 0}
 }
 
@@ -321,15 +356,13 @@ func (s *Solver) incWeights(globalScore *int) {
 }
 
 func (s *Solver) searchRange(makespan int) bool {
-	var iterate bool
 	projSlack := makespan - s.minMakespan
 	if projSlack < 0 {
 		return false
 	}
 	for varId, v := range s.variables {
 		s.variables[varId].ubound = v.minUbound + projSlack
-		delta := s.variables[varId].ubound - v.lbound
-		s.setVariable(varId, s.variables[varId].lbound+rand.Intn(delta+1))
+		s.setVariable(varId, s.initialValue(varId))
 	}
 	score := 0
 	for c := range s.constraints {
@@ -337,42 +370,54 @@ func (s *Solver) searchRange(makespan int) bool {
 		s.constraints[c].score = eval
 		score += eval
 	}
+	s.stats.BestScore = score
 	if score == 0 {
 		return true
 	}
-	s.stop = false
+	// Unweighted score, the sum of the violations, against which stagnation and aspiration are measured
+	s.rawScore, s.bestRawScore = score, score
+	stagnation := 0
+	s.resetTabu()
 	s.varChannels = make([]chan int, s.param.threads)
 	for thread := 0; thread < s.param.threads; thread++ {
 		s.varChannels[thread] = make(chan int)
 	}
 	s.statusChannel = make(chan int)
+	var deadline time.Time
 	if s.param.maxTime > 0 {
-		iterate = true
-		time.AfterFunc(time.Millisecond*time.Duration(s.param.maxTime), func() {
-			iterate = false
-		})
-	} else {
-		iterate = false
+		deadline = time.Now().Add(time.Millisecond * time.Duration(s.param.maxTime))
 	}
-	for tries := 0; tries < s.param.maxIterations || iterate; tries++ {
+	for tries := 0; (tries < s.param.maxIterations || (!deadline.IsZero() && time.Now().Before(deadline))) && !s.cancelled(); tries++ {
 		bestVar := common.UNDEF
 		var bestValue int
 		var tmpConstraintScores []int
 		s.stats.Iterations++
-		s.nextVar = 0
+		s.nextVar, s.stopVar = 0, len(s.variables)
 		for thread := 0; thread < s.param.threads; thread++ {
 			go s.exploreVariables(score, thread)
 		}
+		bestDeviation := 0
 		for i := 0; i < s.param.threads; i++ {
 			thread := <-s.statusChannel
 			threadBestVar := <-s.varChannels[thread]
 			if threadBestVar > common.UNDEF {
 				threadBestValue := <-s.varChannels[thread]
 				bestNewScore := <-s.varChannels[thread]
-				if bestNewScore < score {
+				deviation := <-s.varChannels[thread]
+				better := bestNewScore < score
+				if bestNewScore == score && bestVar > common.UNDEF {
+					// Ties are settled as a single thread would, whatever the order the threads finish in
+					if bestNewScore > 0 && deviation != bestDeviation {
+						better = deviation < bestDeviation
+					} else {
+						better = threadBestVar < bestVar
+					}
+				}
+				if better {
 					bestVar = threadBestVar
 					bestValue = threadBestValue
 					score = bestNewScore
+					bestDeviation = deviation
 					tmpConstraintScores = []int{}
 					token := <-s.varChannels[thread]
 					for token > -1 {
@@ -389,19 +434,41 @@ func (s *Solver) searchRange(makespan int) bool {
 			}
 		}
 		if bestVar > common.UNDEF {
+			s.makeTabu(bestVar, s.variables[bestVar].value)
 			s.setVariable(bestVar, bestValue)
+			s.stats.Assignments++
 			for j := 0; j < len(tmpConstraintScores); j += 2 {
 				c := tmpConstraintScores[j]
 				eval := tmpConstraintScores[j+1]
+				s.rawScore += eval - s.constraints[c].score
 				s.constraints[c].score = eval
 			}
 			if score == 0 {
+				s.bestRawScore = 0
 				break
 			}
 		} else {
+			// Local minimum, escaped by raising the weights of the violated constraints
 			s.incWeights(&score)
+			s.stats.Escapes++
+		}
+		if s.rawScore < s.bestRawScore {
+			s.bestRawScore = s.rawScore
+			stagnation = 0
+		} else {
+			stagnation++
+		}
+		if stagnation >= RESTART_STAGNATION {
+			score = s.restart()
+			s.rawScore = score
+			stagnation = 0
+			if score == 0 {
+				s.bestRawScore = 0
+				break
+			}
 		}
 	}
+	s.stats.BestScore = s.bestRawScore
 	for thread := 0; thread < s.param.threads; thread++ {
 		close(s.varChannels[thread])
 	}
@@ -468,6 +535,9 @@ func (s *Solver) SolveFixedMakespan(makespan int) common.TaskSchedule {
 	s.resetWorkspace()
 	ok := s.searchRange(makespan)
 	if ok {
+		if s.reference != nil {
+			s.pullTowardsReference()
+		}
 		return s.ExportSolution()
 	} else {
 		return nil
@@ -475,19 +545,50 @@ func (s *Solver) SolveFixedMakespan(makespan int) common.TaskSchedule {
 }
 
 func (s *Solver) SolveOptimalMakespan() (int, common.TaskSchedule) {
-	sched := s.SolveFixedMakespan(s.minMakespan)
+	sgsMakespan, sgsSchedule := s.BestScheduleGeneration(DEFAULT_SGS_SAMPLES)
+	if sgsSchedule != nil {
+		s.report(sgsMakespan, sgsSchedule)
+	}
+	lowerBound := s.LowerBound()
+	if sgsSchedule != nil && sgsMakespan == lowerBound && s.reference == nil {
+		// A constructive schedule meeting the lower bound is already optimal
+		return sgsMakespan, sgsSchedule
+	}
+	sched := s.SolveFixedMakespan(lowerBound)
 	if sched != nil {
-		return s.minMakespan, sched
+		s.report(lowerBound, sched)
+		return lowerBound, sched
 	}
-	lBound := s.minMakespan - 1
+	// Every makespan below the bound is infeasible, the search stops as soon as it is met
+	lBound := lowerBound - 1
 	uBound := s.sumTasksDurations()
 	bestMakespan := uBound
-	bestSchedule := s.SolveFixedMakespan(uBound)
+	var bestSchedule common.TaskSchedule
+	warmMakespan, warmSchedule := s.warmStartBound()
+	if warmSchedule != nil && warmMakespan < uBound {
+		bestMakespan = warmMakespan
+		bestSchedule = warmSchedule
+		uBound = warmMakespan
+	} else if sgsSchedule != nil && sgsMakespan <= uBound {
+		bestMakespan = sgsMakespan
+		bestSchedule = sgsSchedule
+		uBound = sgsMakespan
+		if s.reference != nil {
+			// Prefer a schedule of the same makespan that stays close to the previous one
+			sched = s.SolveFixedMakespan(uBound)
+			if sched != nil {
+				bestSchedule = sched
+			}
+		}
+	} else {
+		bestSchedule = s.SolveFixedMakespan(uBound)
+	}
 	if bestSchedule == nil {
 		// The problem is impossible, there are likely constraints inconsistencies
 		return common.UNDEF, nil
 	}
-	for uBound-lBound > 1 {
+	s.report(bestMakespan, bestSchedule)
+	for uBound = s.boundedBy(uBound); uBound-lBound > 1 && !s.cancelled(); uBound = s.boundedBy(uBound) {
 		makespan := (lBound + uBound) / 2
 		sched := s.SolveFixedMakespan(makespan)
 		if sched == nil {
@@ -502,11 +603,14 @@ func (s *Solver) SolveOptimalMakespan() (int, common.TaskSchedule) {
 				bestSchedule = s.ExportSolution()
 				uBound = compMakespan
 			}
+			s.report(bestMakespan, bestSchedule)
 		}
 		// Completely reset the solver object for the next iteration
 		p := s.param
+		reference, initial, shared, ctx, progress, random := s.reference, s.initial, s.shared, s.ctx, s.progress, s.random
 		s = NewSolver(s.model)
 		s.SetParameters(p.maxIterations, p.threads, p.step, p.maxTime)
+		s.reference, s.initial, s.shared, s.ctx, s.progress, s.random = reference, initial, shared, ctx, progress, random
 	}
 	return bestMakespan, bestSchedule
 }
@@ -516,6 +620,23 @@ func (s *Solver) Solve() (int, common.TaskSchedule) {
 	return s.SolveOptimalMakespan()
 }
 
+func (s *Solver) SetSeed(seed int64) {
+	// The same seed, model and number of threads give the same schedule
+	s.random = rand.New(rand.NewSource(seed))
+}
+
+func (s *Solver) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+func (s *Solver) interrupted() bool {
+	return s.ctx != nil && s.ctx.Err() != nil
+}
+
+func (s *Solver) Stats() Statistics {
+	return s.stats
+}
+
 func (s *Solver) ReportStats() string {
 	return fmt.Sprintf("%+v\n", s.stats)
 }
@@ -0,0 +1,64 @@
+/****************************************************************************************
+PMRobo - A lightweight and efficient multi-threaded project scheduling engine
+Copyright (C) 2023  Rui Alves
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program.  If not, see <https://www.gnu.org/licenses/>.
+****************************************************************************************/
+
+package solver
+
+import (
+	"goproj/common"
+)
+
+const (
+	TABU_TENURE        = 7    // Iterations during which a variable may not return to the value it left
+	RESTART_STAGNATION = 1000 // Iterations without a better assignment before restarting the search
+)
+
+func (s *Solver) resetTabu() {
+	s.tabu = make([]map[int]int, len(s.variables))
+	for v := range s.tabu {
+		s.tabu[v] = map[int]int{}
+	}
+}
+
+func (s *Solver) isTabu(v int, x int) bool {
+	expiry, exists := s.tabu[v][x]
+	return exists && expiry > s.stats.Iterations
+}
+
+func (s *Solver) makeTabu(v int, x int) {
+	s.tabu[v][x] = s.stats.Iterations + TABU_TENURE
+}
+
+func (s *Solver) restart() int {
+	// Random values for every free variable and fresh weights, the tabu memory is forgotten
+	for v := range s.variables {
+		if !s.isFixed(v) {
+			lbound := s.variables[v].lbound
+			s.setVariable(v, lbound+s.random.Intn(s.variables[v].ubound-lbound+1))
+		}
+	}
+	score := 0
+	for c := range s.constraints {
+		eval := s.evaluate(c, common.UNDEF, common.UNDEF)
+		s.constraints[c].score = eval
+		s.constraints[c].weight = 1
+		score += eval
+	}
+	s.resetTabu()
+	s.stats.Restarts++
+	return score
+}
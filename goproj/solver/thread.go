@@ -26,6 +26,7 @@ func (s *Solver) exploreVariables(score int, thread int) {
 	bestVar := common.UNDEF
 	var bestValue int
 	bestNewScore := score
+	bestDeviation := 0
 	var newScore int
 	var bestUpdatedScores []int
 	numVariables := len(s.variables)
@@ -37,10 +38,13 @@ varLoop:
 			s.nextVar++
 		}
 		s.mutexVar.Unlock()
-		if v == numVariables {
+		s.mutexStop.Lock()
+		stopped := v > s.stopVar
+		s.mutexStop.Unlock()
+		if v == numVariables || stopped {
 			break varLoop
 		}
-		if s.variables[v].lbound == s.variables[v].ubound {
+		if s.isFixed(v) {
 			continue
 		}
 		x0 := s.variables[v].value
@@ -54,8 +58,9 @@ varLoop:
 		}
 		for x := lbound; x <= ubound; x++ {
 			newScore = score
+			newRawScore := s.rawScore
 			s.mutexStop.Lock()
-			if s.stop { // Check STOP flag from other threads
+			if v > s.stopVar || s.cancelled() { // Another thread solved it with a lower variable, or a cancelled or finished solve
 				s.mutexStop.Unlock()
 				break varLoop
 			}
@@ -70,6 +75,7 @@ varLoop:
 				}
 				eval := s.evaluate(c, v, x)
 				newScore += (eval - s.constraints[c].score) * s.constraints[c].weight
+				newRawScore += eval - s.constraints[c].score
 				updatedScores = append(updatedScores, c)
 				updatedScores = append(updatedScores, eval)
 			}
@@ -79,6 +85,7 @@ varLoop:
 				for c := i; c <= f; c++ {
 					eval := s.evaluate(c, v, x)
 					newScore += (eval - s.constraints[c].score) * s.constraints[c].weight
+					newRawScore += eval - s.constraints[c].score
 					updatedScores = append(updatedScores, c)
 					updatedScores = append(updatedScores, eval)
 				}
@@ -88,19 +95,30 @@ varLoop:
 				for c := i; c <= f; c++ {
 					eval := s.evaluate(c, v, x)
 					newScore += (eval - s.constraints[c].score) * s.constraints[c].weight
+					newRawScore += eval - s.constraints[c].score
 					updatedScores = append(updatedScores, c)
 					updatedScores = append(updatedScores, eval)
 				}
 			}
 			updatedScores = append(updatedScores, -1)
-			if newScore < bestNewScore {
+			if s.isTabu(v, x) && newRawScore >= s.bestRawScore {
+				// Aspiration: a tabu move is only taken when it leads to the best assignment so far
+				continue
+			}
+			// On a previous schedule, equally scored moves are settled by the deviation from it
+			deviation := s.deviationChange(v, x)
+			if newScore < bestNewScore || (newScore == bestNewScore && bestVar > common.UNDEF && deviation < bestDeviation) {
 				bestVar = v
 				bestValue = x
 				bestNewScore = newScore
+				bestDeviation = deviation
 				bestUpdatedScores = updatedScores
 				if newScore == 0 {
+					// Stop the threads exploring higher variables, lower ones may still find a solution first
 					s.mutexStop.Lock()
-					s.stop = true // Raise STOP flag to other threads
+					if v < s.stopVar {
+						s.stopVar = v
+					}
 					s.mutexStop.Unlock()
 					break varLoop
 				}
@@ -112,6 +130,7 @@ varLoop:
 	if bestVar > common.UNDEF {
 		s.varChannels[thread] <- bestValue
 		s.varChannels[thread] <- bestNewScore
+		s.varChannels[thread] <- bestDeviation
 		for _, updatedScore := range bestUpdatedScores {
 			s.varChannels[thread] <- updatedScore
 		}
@@ -20,26 +20,55 @@ package main
 
 import (
 	"goproj/project"
+	"goproj/solver"
+	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
 
 const (
-	configFile  = "pmrobo.xml"
-	defaultPort = 9100
+	configFile     = "pmrobo.xml"
+	defaultPort    = 9100
+	defaultMaxRuns = 10000
 )
 
+var defaultIterations = []int{1000, 10000, 100000}
+
 type Config struct {
-	XMLName xml.Name `xml:"config"`
-	Threads int      `xml:"threads"`
-	Times   TimeList `xml:"times"`
-	Step    int      `xml:"step"`
-	Port    int      `xml:"port"`
+	XMLName    xml.Name      `xml:"config"`
+	Threads    int           `xml:"threads"`
+	Times      TimeList      `xml:"times"`
+	Iterations IterationList `xml:"iterations"`
+	Step       int           `xml:"step"`
+	Port       int           `xml:"port"`
+	Algorithm  string        `xml:"algorithm"`
+	Seed       *int64        `xml:"seed"`
+	MaxRuns    int           `xml:"max-runs"`
+	Portfolio  Portfolio     `xml:"portfolio"`
+}
+
+type Portfolio struct {
+	Mode    string            `xml:"mode,attr"`
+	Members []PortfolioMember `xml:"member"`
+}
+
+type PortfolioMember struct {
+	Algorithm string `xml:"algorithm,attr"`
+	Threads   int    `xml:"threads,attr"`
+	Step      int    `xml:"step,attr"`
+	Seed      int64  `xml:"seed,attr"`
+}
+
+type outcome struct {
+	solution bool
+	errStr   string
 }
 
 type TimeList struct {
@@ -47,6 +76,11 @@ type TimeList struct {
 	Time    []int    `xml:"time"`
 }
 
+type IterationList struct {
+	XMLName   xml.Name `xml:"iterations"`
+	Iteration []int    `xml:"iteration"`
+}
+
 func LoadConfig(filename string) (*Config, string) {
 	var settings Config
 	xmlFile, err := os.Open(filename)
@@ -62,9 +96,90 @@ func LoadConfig(filename string) (*Config, string) {
 	if settings.Port == 0 {
 		settings.Port = defaultPort
 	}
+	if settings.MaxRuns <= 0 {
+		settings.MaxRuns = defaultMaxRuns
+	}
+	if len(settings.Iterations.Iteration) == 0 {
+		settings.Iterations.Iteration = defaultIterations
+	}
 	return &settings, ""
 }
 
+func (config *Config) setFirstLimit(proj *project.Project) {
+	// Analyses solving many schedules in a row stick to the first step of the ladder
+	maxTime := 0
+	if len(config.Times.Time) > 0 {
+		maxTime = config.Times.Time[0]
+	}
+	proj.SetSolverParameters(0, config.Threads, config.Step, 0)
+	proj.SetSolverLimit(maxTime, config.Iterations.Iteration[0])
+}
+
+func (config *Config) simulationRuns(c *gin.Context) (int, bool) {
+	// The number of simulated schedules asked for, the default one kept within the configured maximum
+	runs, err := strconv.Atoi(c.DefaultQuery("runs", "0"))
+	if err != nil {
+		c.String(http.StatusBadRequest, fmt.Sprintf("Invalid number of runs '%s'", c.Query("runs")))
+		return 0, false
+	}
+	if runs <= 0 {
+		runs = project.DEFAULT_SIMULATION_RUNS
+		if runs > config.MaxRuns {
+			runs = config.MaxRuns
+		}
+	} else if runs > config.MaxRuns {
+		c.String(http.StatusBadRequest, fmt.Sprintf("Number of runs exceeds the maximum of %d", config.MaxRuns))
+		return 0, false
+	}
+	return runs, true
+}
+
+func (config *Config) portfolioMembers() []solver.PortfolioMember {
+	members := []solver.PortfolioMember{}
+	for _, m := range config.Portfolio.Members {
+		members = append(members, solver.PortfolioMember{Algorithm: m.Algorithm, Threads: m.Threads, Step: m.Step, Seed: m.Seed})
+	}
+	return members
+}
+
+func scheduleProject(ctx context.Context, proj *project.Project, config *Config) outcome {
+	// The ladder of times, or of iterations for a seeded request, until a schedule is found, which is then checked
+	proj.SetSolverParameters(0, config.Threads, config.Step, 0)
+	if proj.ScheduleLadder(ctx, config.Times.Time, config.Iterations.Iteration) {
+		return outcome{true, proj.CheckScheduleConsistency()}
+	}
+	return outcome{false, ""}
+}
+
+func importProject(c *gin.Context, config *Config) *project.Project {
+	var p project.RootNode
+	c.Header("Access-Control-Allow-Origin", "*")
+	err := c.BindXML(&p)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return nil
+	}
+	if p.Algorithm == "" {
+		// The algorithm named in the request prevails over the configured one
+		p.Algorithm = config.Algorithm
+	}
+	if p.Seed == nil {
+		// Likewise for the seed, without one every request searches anew
+		p.Seed = config.Seed
+	}
+	proj, errStr := project.ImportFromDirectXMLTree(p)
+	if errStr != "" {
+		c.String(http.StatusBadRequest, errStr)
+		return nil
+	}
+	errStr = proj.SetPortfolio(config.portfolioMembers(), config.Portfolio.Mode)
+	if errStr != "" {
+		c.String(http.StatusBadRequest, errStr)
+		return nil
+	}
+	return proj
+}
+
 func main() {
 	config, err := LoadConfig(configFile)
 	if config.Threads == 0 {
@@ -76,39 +191,162 @@ func main() {
 		fmt.Fprint(os.Stderr, err)
 		return
 	}
+	if config.Algorithm != "" && !solver.HasAlgorithm(config.Algorithm) {
+		fmt.Fprintf(os.Stderr, "Unknown solver algorithm '%s'", config.Algorithm)
+		return
+	}
+	err = solver.CheckPortfolio(config.portfolioMembers(), config.Portfolio.Mode, config.Seed != nil && config.Algorithm == solver.PORTFOLIO_ALGORITHM)
+	if err != "" {
+		fmt.Fprint(os.Stderr, err)
+		return
+	}
 	r := gin.Default()
 	r.POST("/schedule", func(c *gin.Context) {
-		var p project.RootNode
-		c.Header("Access-Control-Allow-Origin", "*")
-		err := c.BindXML(&p)
-		if err == nil {
-			proj, errStr := project.ImportFromDirectXMLTree(p)
-			if errStr == "" {
-				solution := false
-				for _, maxTime := range config.Times.Time {
-					proj.SetSolverParameters(0, config.Threads, config.Step, maxTime)
-					fmt.Printf("Trying with time=%d\n", maxTime)
-					if proj.Schedule(project.FIND_OPTIMAL) {
-						errStr = proj.CheckScheduleConsistency()
-						if errStr == "" {
-							c.Header("Content-Type", "application/xml")
-							c.String(http.StatusOK, proj.ExportScheduleToStringXML())
-						} else {
-							c.String(http.StatusBadRequest, "Reserved error.")
-						}
-						solution = true
-						break
-					}
-				}
-				if !solution {
-					c.String(http.StatusBadRequest, "No schedule found. Project constraints may be too complex or even inconsistent.")
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		ctx := c.Request.Context()
+		result := scheduleProject(ctx, proj, config)
+		if result.solution && result.errStr == "" && c.Query("format") == "csv" {
+			usage, _ := proj.AnalyzeResourceUsage()
+			c.Header("Content-Type", "text/csv")
+			c.String(http.StatusOK, usage.ExportToCSV())
+		} else if result.solution && result.errStr == "" {
+			c.Header("Content-Type", "application/xml")
+			c.String(http.StatusOK, proj.ExportScheduleToStringXML())
+		} else if result.solution {
+			c.String(http.StatusBadRequest, "Reserved error.")
+		} else if ctx.Err() == nil {
+			// Nobody is waiting for the diagnosis of a dropped request
+			c.Header("Content-Type", "application/xml")
+			c.String(http.StatusBadRequest, proj.Diagnose(project.FIND_OPTIMAL).ExportToStringXML())
+		}
+	})
+	r.POST("/schedule/stream", func(c *gin.Context) {
+		// Server-sent events: one per improved schedule, then the final schedule or the diagnosis
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		ctx := c.Request.Context()
+		progress := make(chan solver.Progress)
+		done := make(chan outcome, 1)
+		proj.SetProgress(func(p solver.Progress) {
+			select {
+			case progress <- p:
+			case <-ctx.Done():
+			}
+		})
+		go func() {
+			done <- scheduleProject(ctx, proj, config)
+		}()
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case p := <-progress:
+				c.SSEvent("progress", fmt.Sprintf("<progress makespan=\"%d\" elapsed-ms=\"%d\" iterations=\"%d\"/>", p.Makespan, p.Elapsed.Milliseconds(), p.Iterations))
+				return true
+			case result := <-done:
+				if result.solution && result.errStr == "" {
+					c.SSEvent("schedule", proj.ExportScheduleToStringXML())
+				} else if result.solution {
+					c.SSEvent("error", "Reserved error.")
+				} else {
+					c.SSEvent("diagnosis", proj.Diagnose(project.FIND_OPTIMAL).ExportToStringXML())
 				}
-			} else {
-				c.String(http.StatusBadRequest, errStr)
+				return false
+			case <-ctx.Done():
+				return false
 			}
-		} else {
-			c.String(http.StatusBadRequest, err.Error())
+		})
+	})
+	r.POST("/simulate", func(c *gin.Context) {
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		runs, valid := config.simulationRuns(c)
+		if !valid {
+			return
+		}
+		// The runs stop as soon as the client goes away
+		ctx := c.Request.Context()
+		report, errStr := proj.SimulateContext(ctx, runs)
+		if ctx.Err() != nil {
+			return
+		}
+		if errStr != "" {
+			c.String(http.StatusBadRequest, errStr)
+			return
+		}
+		c.Header("Content-Type", "application/xml")
+		c.String(http.StatusOK, report.ExportToStringXML())
+	})
+	r.POST("/risk", func(c *gin.Context) {
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		runs, valid := config.simulationRuns(c)
+		if !valid {
+			return
+		}
+		// The runs stop as soon as the client goes away
+		ctx := c.Request.Context()
+		report, errStr := proj.AnalyzeRiskContext(ctx, runs)
+		if ctx.Err() != nil {
+			return
+		}
+		if errStr != "" {
+			c.String(http.StatusBadRequest, errStr)
+			return
+		}
+		c.Header("Content-Type", "application/xml")
+		c.String(http.StatusOK, report.ExportToStringXML())
+	})
+	r.POST("/crash", func(c *gin.Context) {
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		target, _ := strconv.Atoi(c.Query("target"))
+		config.setFirstLimit(proj)
+		report, errStr := proj.Crash(target)
+		if errStr != "" {
+			c.String(http.StatusBadRequest, errStr)
+			return
+		}
+		c.Header("Content-Type", "application/xml")
+		c.String(http.StatusOK, report.ExportToStringXML())
+	})
+	r.POST("/critical-chain", func(c *gin.Context) {
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		cut, _ := strconv.Atoi(c.DefaultQuery("cut", "0"))
+		config.setFirstLimit(proj)
+		report, errStr := proj.CriticalChain(c.Query("sizing"), cut)
+		if errStr != "" {
+			c.String(http.StatusBadRequest, errStr)
+			return
+		}
+		c.Header("Content-Type", "application/xml")
+		c.String(http.StatusOK, report.ExportToStringXML())
+	})
+	r.POST("/scenarios", func(c *gin.Context) {
+		proj := importProject(c, config)
+		if proj == nil {
+			return
+		}
+		config.setFirstLimit(proj)
+		report, errStr := proj.RunScenarios()
+		if errStr != "" {
+			c.String(http.StatusBadRequest, errStr)
+			return
 		}
+		c.Header("Content-Type", "application/xml")
+		c.String(http.StatusOK, report.ExportToStringXML())
 	})
 	r.Run(fmt.Sprintf(":%d", config.Port))
 }